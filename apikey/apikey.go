@@ -0,0 +1,196 @@
+// Package apikey provides API key authentication for machine clients. Keys
+// are stored hashed and map to auth.Claims so role checks work the same as
+// for tokens.
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gitamped/seed/auth"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// These are the errors returned when authenticating a key.
+var (
+	ErrNotFound   = errors.New("api key not found")
+	ErrInvalidKey = errors.New("api key is invalid")
+	ErrExpired    = errors.New("api key is expired")
+)
+
+// touchInterval limits how often the last used time is written.
+const touchInterval = time.Minute
+
+// Key is the stored state of an API key. Only a hash of the secret part of
+// the key is stored.
+type Key struct {
+	// ID identifies the key and is part of the key itself.
+	ID string
+	// Prefix identifies the kind of key, e.g. "seed" or "partner".
+	Prefix    string
+	Hash      []byte
+	Subject   string
+	Roles     []string
+	ExpiresAt time.Time
+	LastUsed  time.Time
+}
+
+// Store declares a method set of behavior for looking up API keys.
+// Implementations must be safe for concurrent use.
+type Store interface {
+	Get(ctx context.Context, id string) (Key, error)
+	// Touch records when the key was last used.
+	Touch(ctx context.Context, id string, t time.Time) error
+}
+
+// Generate creates a new key with the prefix. The returned plaintext key,
+// of the form <prefix>_<id>_<secret>, must be given to the client; it can
+// not be recovered from the Key.
+func Generate(prefix string) (string, Key, error) {
+	if prefix == "" || strings.Contains(prefix, "_") {
+		return "", Key{}, errors.New("prefix must be non empty and not contain _")
+	}
+
+	id := make([]byte, 8)
+	secret := make([]byte, 32)
+	if _, err := rand.Read(id); err != nil {
+		return "", Key{}, fmt.Errorf("generating key: %w", err)
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return "", Key{}, fmt.Errorf("generating key: %w", err)
+	}
+
+	k := Key{
+		ID:     hex.EncodeToString(id),
+		Prefix: prefix,
+	}
+	s := base64.RawURLEncoding.EncodeToString(secret)
+	k.Hash = hash(s)
+
+	return fmt.Sprintf("%s_%s_%s", k.Prefix, k.ID, s), k, nil
+}
+
+// Parse splits a plaintext key into its prefix, id and secret.
+func Parse(plaintext string) (prefix, id, secret string, err error) {
+	parts := strings.SplitN(plaintext, "_", 3)
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return "", "", "", ErrInvalidKey
+	}
+	return parts[0], parts[1], parts[2], nil
+}
+
+// Authenticate checks the plaintext key against the store and returns the
+// claims of its owner.
+func Authenticate(ctx context.Context, s Store, plaintext string) (auth.Claims, error) {
+	prefix, id, secret, err := Parse(plaintext)
+	if err != nil {
+		return auth.Claims{}, err
+	}
+
+	k, err := s.Get(ctx, id)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return auth.Claims{}, ErrInvalidKey
+		}
+		return auth.Claims{}, fmt.Errorf("looking up api key: %w", err)
+	}
+
+	if k.Prefix != prefix || subtle.ConstantTimeCompare(hash(secret), k.Hash) != 1 {
+		return auth.Claims{}, ErrInvalidKey
+	}
+
+	now := time.Now()
+	if !k.ExpiresAt.IsZero() && now.After(k.ExpiresAt) {
+		return auth.Claims{}, ErrExpired
+	}
+
+	if now.Sub(k.LastUsed) >= touchInterval {
+		if err := s.Touch(ctx, k.ID, now); err != nil {
+			return auth.Claims{}, fmt.Errorf("touching api key: %w", err)
+		}
+	}
+
+	claims := auth.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject: k.Subject,
+		},
+		Roles: k.Roles,
+	}
+	if !k.ExpiresAt.IsZero() {
+		claims.ExpiresAt = jwt.NewNumericDate(k.ExpiresAt)
+	}
+
+	return claims, nil
+}
+
+// hash returns the hash of the secret part of a key.
+func hash(secret string) []byte {
+	sum := sha256.Sum256([]byte(secret))
+	return sum[:]
+}
+
+// =============================================================================
+
+// MemoryStore is an in-memory Store.
+type MemoryStore struct {
+	mu    sync.RWMutex
+	store map[string]Key
+}
+
+// NewMemoryStore constructs an empty MemoryStore ready for use.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		store: make(map[string]Key),
+	}
+}
+
+// Add adds a key to the store.
+func (ms *MemoryStore) Add(k Key) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ms.store[k.ID] = k
+}
+
+// Remove removes a key from the store.
+func (ms *MemoryStore) Remove(id string) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	delete(ms.store, id)
+}
+
+// Get implements the Store interface.
+func (ms *MemoryStore) Get(ctx context.Context, id string) (Key, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	k, found := ms.store[id]
+	if !found {
+		return Key{}, ErrNotFound
+	}
+	return k, nil
+}
+
+// Touch implements the Store interface.
+func (ms *MemoryStore) Touch(ctx context.Context, id string, t time.Time) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	k, found := ms.store[id]
+	if !found {
+		return ErrNotFound
+	}
+	k.LastUsed = t
+	ms.store[id] = k
+	return nil
+}
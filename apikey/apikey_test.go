@@ -0,0 +1,80 @@
+package apikey_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gitamped/seed/apikey"
+	"github.com/gitamped/seed/auth"
+	"github.com/gitamped/seed/mid"
+	"github.com/gitamped/seed/server"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+func Test_APIKey(t *testing.T) {
+	t.Log("Given the need to authenticate machine clients with api keys.")
+	{
+		plaintext, key, err := apikey.Generate("seed")
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to generate a key: %v", failed, err)
+		}
+		key.Subject = "partner-integration"
+		key.Roles = []string{auth.RoleUser}
+
+		store := apikey.NewMemoryStore()
+		store.Add(key)
+
+		mw := append([]mid.Middleware{mid.APIKeyMiddleware(store, "")}, mid.CommonMiddleware...)
+		s := server.NewServer(mw)
+		s.Register("PartnerService", "Call", server.RPCEndpoint{
+			Roles: []string{auth.RoleUser},
+			Handler: func(g server.GenericRequest, b []byte) (any, error) {
+				return struct{ Subject string }{g.Claims.Subject}, nil
+			},
+		})
+
+		ttable := []struct {
+			TestTitle          string
+			Key                string
+			ExpectedStatusCode int
+		}{
+			{"When passed a valid key", plaintext, http.StatusOK},
+			{"When passed a key with the wrong secret", "seed_" + key.ID + "_wrong", http.StatusUnauthorized},
+			{"When passed a key with the wrong prefix", "other" + plaintext[len("seed"):], http.StatusUnauthorized},
+		}
+
+		for testID, td := range ttable {
+			t.Logf("\tTest %d:\t%s.", testID, td.TestTitle)
+			{
+				r := httptest.NewRequest(http.MethodPost, "/v1/PartnerService.Call", bytes.NewBufferString(`{}`))
+				r.Header.Set("X-API-Key", td.Key)
+				w := httptest.NewRecorder()
+				s.ServeHTTP(w, r)
+
+				if w.Code != td.ExpectedStatusCode {
+					t.Fatalf("\t%s\tTest %d:\tShould receive a status code of %d for the response : %v", failed, testID, td.ExpectedStatusCode, w.Code)
+				}
+				t.Logf("\t%s\tTest %d:\tShould receive a status code of %d for the response.", success, testID, td.ExpectedStatusCode)
+			}
+		}
+
+		testID := len(ttable)
+		t.Logf("\tTest %d:\tWhen a key has been used.", testID)
+		{
+			k, _ := store.Get(context.Background(), key.ID)
+			if time.Since(k.LastUsed) > time.Minute {
+				t.Fatalf("\t%s\tTest %d:\tShould track when the key was last used : %v", failed, testID, k.LastUsed)
+			}
+			t.Logf("\t%s\tTest %d:\tShould track when the key was last used.", success, testID)
+		}
+	}
+}
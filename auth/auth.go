@@ -10,13 +10,21 @@ import (
 	"crypto/rsa"
 	"errors"
 	"fmt"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
 )
 
 // ErrForbidden is returned when a auth issue is identified.
 var ErrForbidden = errors.New("attempted action is not allowed")
 
+// ErrVerifyOnly is returned when signing with an Auth that has no keys to
+// sign with.
+var ErrVerifyOnly = errors.New("auth can only verify tokens")
+
 // KeyLookup declares a method set of behavior for looking up
 // private and public keys for JWT use.
 type KeyLookup interface {
@@ -27,66 +35,177 @@ type KeyLookup interface {
 // Auth is used to authenticate clients. It can generate a token for a
 // set of user claims and recreate the claims by parsing the token.
 type Auth struct {
-	activeKID string
-	keyLookup KeyLookup
-	method    jwt.SigningMethod
-	keyFunc   func(t *jwt.Token) (any, error)
-	parser    *jwt.Parser
+	mu         sync.RWMutex
+	activeKID  string
+	signingKey func(kid string) (any, jwt.SigningMethod, error)
+	keyFunc    func(t *jwt.Token) (any, error)
+	parser     *jwt.Parser
+	validation Validation
+	revocation RevocationStore
+	privileged []string
 }
 
-// New creates an Auth to support authentication/authorization.
+// New creates an Auth to support authentication/authorization using RS256.
 func New(activeKID string, keyLookup KeyLookup) (*Auth, error) {
+	return NewWithSigners(activeKID, rsaLookup{keyLookup}, "RS256")
+}
 
-	// The activeKID represents the private key used to signed new tokens.
-	_, err := keyLookup.PrivateKey(activeKID)
-	if err != nil {
-		return nil, errors.New("active KID does not exist in store")
+// NewWithSigners creates an Auth that signs tokens with the algorithm of the
+// active kid and accepts tokens signed with any of the allowed algorithms.
+// Default: only the algorithm of the active kid is allowed.
+func NewWithSigners(activeKID string, signers SignerLookup, algs ...string) (*Auth, error) {
+	if len(algs) == 0 {
+		_, alg, err := signers.SigningKey(activeKID)
+		if err != nil {
+			return nil, errors.New("active KID does not exist in store")
+		}
+		algs = []string{alg}
 	}
 
-	method := jwt.GetSigningMethod("RS256")
-	if method == nil {
-		return nil, errors.New("configuring algorithm RS256")
+	allowed := make(map[string]bool)
+	for _, a := range algs {
+		if !asymmetricAlgs[a] {
+			return nil, fmt.Errorf("algorithm %s is not supported", a)
+		}
+		allowed[a] = true
 	}
 
-	keyFunc := func(t *jwt.Token) (any, error) {
-		kid, ok := t.Header["kid"]
-		if !ok {
-			return nil, errors.New("missing key id (kid) in token header")
+	signingKey := func(kid string) (any, jwt.SigningMethod, error) {
+		signer, alg, err := signers.SigningKey(kid)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !allowed[alg] {
+			return nil, nil, fmt.Errorf("algorithm %s is not allowed", alg)
 		}
-		kidID, ok := kid.(string)
-		if !ok {
-			return nil, errors.New("user token key id (kid) must be string")
+		if err := checkKey(alg, signer.Public()); err != nil {
+			return nil, nil, err
 		}
-		return keyLookup.PublicKey(kidID)
+		return signer, signingMethod(alg), nil
 	}
 
 	// Create the token parser to use. The algorithm used to sign the JWT must be
 	// validated to avoid a critical vulnerability:
 	// https://auth0.com/blog/critical-vulnerabilities-in-json-web-token-libraries/
-	parser := jwt.NewParser(jwt.WithValidMethods([]string{"RS256"}))
+	// Claims are checked by ValidateToken so leeway can be applied.
+	parser := jwt.NewParser(jwt.WithValidMethods(algs), jwt.WithoutClaimsValidation())
+
+	return newAuth(activeKID, signingKey, publicKeyFunc(signers), parser)
+}
+
+// NewVerifier creates an Auth that can only validate tokens, for services
+// that consume tokens without holding any private key. Tokens must be signed
+// with one of the allowed algorithms.
+func NewVerifier(keys PublicKeyLookup, algs ...string) (*Auth, error) {
+	if len(algs) == 0 {
+		return nil, errors.New("at least one algorithm must be allowed")
+	}
+	for _, a := range algs {
+		if !asymmetricAlgs[a] {
+			return nil, fmt.Errorf("algorithm %s is not supported", a)
+		}
+	}
 
 	a := Auth{
-		activeKID: activeKID,
-		keyLookup: keyLookup,
-		method:    method,
-		keyFunc:   keyFunc,
-		parser:    parser,
+		keyFunc: publicKeyFunc(keys),
+		parser:  jwt.NewParser(jwt.WithValidMethods(algs), jwt.WithoutClaimsValidation()),
 	}
 
 	return &a, nil
 }
 
+// publicKeyFunc returns the func used to look up the key of a token.
+func publicKeyFunc(keys PublicKeyLookup) func(t *jwt.Token) (any, error) {
+	return func(t *jwt.Token) (any, error) {
+		kid, err := tokenKID(t)
+		if err != nil {
+			return nil, err
+		}
+		pub, keyAlg, err := keys.VerifyingKey(kid)
+		if err != nil {
+			return nil, err
+		}
+
+		// The token must be signed with the algorithm of its key, not only
+		// one of the allowed algorithms.
+		if t.Method.Alg() != keyAlg {
+			return nil, fmt.Errorf("token algorithm %s does not match key", t.Method.Alg())
+		}
+		if err := checkKey(keyAlg, pub); err != nil {
+			return nil, err
+		}
+		return pub, nil
+	}
+}
+
+// newAuth constructs an Auth after checking the active kid can sign.
+func newAuth(activeKID string, signingKey func(kid string) (any, jwt.SigningMethod, error), keyFunc func(t *jwt.Token) (any, error), parser *jwt.Parser) (*Auth, error) {
+
+	// The activeKID represents the private key used to signed new tokens.
+	if _, _, err := signingKey(activeKID); err != nil {
+		return nil, fmt.Errorf("active KID does not exist in store: %w", err)
+	}
+
+	a := Auth{
+		activeKID:  activeKID,
+		signingKey: signingKey,
+		keyFunc:    keyFunc,
+		parser:     parser,
+	}
+
+	return &a, nil
+}
+
+// Rotate makes kid the key used to sign new tokens. Tokens signed with
+// previous keys remain valid while those keys are in the store.
+func (a *Auth) Rotate(kid string) error {
+	if a.signingKey == nil {
+		return ErrVerifyOnly
+	}
+	if _, _, err := a.signingKey(kid); err != nil {
+		return fmt.Errorf("rotating to KID %s: %w", kid, err)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.activeKID = kid
+	return nil
+}
+
 // GenerateToken generates a signed JWT token string representing the user Claims.
+// A unique token id (jti), and the issued at time (iat), are assigned when
+// the claims do not have them, so tokens can be revoked.
 func (a *Auth) GenerateToken(claims Claims) (string, error) {
-	token := jwt.NewWithClaims(a.method, claims)
-	token.Header["kid"] = a.activeKID
+	if a.signingKey == nil {
+		return "", ErrVerifyOnly
+	}
 
-	privateKey, err := a.keyLookup.PrivateKey(a.activeKID)
+	if claims.ID == "" {
+		claims.ID = uuid.NewString()
+	}
+	if claims.IssuedAt == nil {
+		claims.IssuedAt = jwt.NewNumericDate(time.Now())
+	}
+
+	return a.sign(claims)
+}
+
+// sign signs the claims with the active key.
+func (a *Auth) sign(claims jwt.Claims) (string, error) {
+	a.mu.RLock()
+	kid := a.activeKID
+	a.mu.RUnlock()
+
+	key, method, err := a.signingKey(kid)
 	if err != nil {
 		return "", errors.New("kid lookup failed")
 	}
 
-	str, err := token.SignedString(privateKey)
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+
+	str, err := token.SignedString(key)
 	if err != nil {
 		return "", fmt.Errorf("signing token: %w", err)
 	}
@@ -100,12 +219,37 @@ func (a *Auth) ValidateToken(tokenStr string) (Claims, error) {
 	var claims Claims
 	token, err := a.parser.ParseWithClaims(tokenStr, &claims, a.keyFunc)
 	if err != nil {
-		return Claims{}, fmt.Errorf("parsing token: %w", err)
+		return Claims{}, fmt.Errorf("parsing token: %w", parseError(err))
 	}
 
 	if !token.Valid {
 		return Claims{}, errors.New("invalid token")
 	}
 
+	if err := a.validation.check(claims.RegisteredClaims, time.Now()); err != nil {
+		return Claims{}, fmt.Errorf("validating token: %w", err)
+	}
+
+	if err := a.CheckRevoked(claims); err != nil {
+		return Claims{}, fmt.Errorf("validating token: %w", err)
+	}
+
+	if parts := strings.Split(tokenStr, "."); len(parts) == 3 {
+		claims.raw, _ = jwt.DecodeSegment(parts[1])
+	}
+
 	return claims, nil
 }
+
+// tokenKID returns the key id from the token header.
+func tokenKID(t *jwt.Token) (string, error) {
+	kid, ok := t.Header["kid"]
+	if !ok {
+		return "", errors.New("missing key id (kid) in token header")
+	}
+	kidID, ok := kid.(string)
+	if !ok {
+		return "", errors.New("user token key id (kid) must be string")
+	}
+	return kidID, nil
+}
@@ -6,12 +6,19 @@ package auth_test
 	Copyright (c) Ardan Labs
 */
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
+	"errors"
+	"io"
 	"testing"
 	"time"
 
 	"github.com/gitamped/seed/auth"
+	"github.com/gitamped/seed/keystore"
 	"github.com/golang-jwt/jwt/v4"
 )
 
@@ -79,6 +86,385 @@ func Test_Auth(t *testing.T) {
 	}
 }
 
+func Test_Algorithms(t *testing.T) {
+	t.Log("Given the need to sign tokens with ECDSA and EdDSA keys.")
+	{
+		ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		ec384Key, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+		_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+
+		ks := keystore.New()
+		for kid, key := range map[string]crypto.Signer{"es256": ecKey, "es384": ec384Key, "eddsa": edKey} {
+			if err := ks.AddSigner(key, kid); err != nil {
+				t.Fatalf("\t%s\tShould be able to add a %s key: %v", failed, kid, err)
+			}
+		}
+
+		claims := auth.Claims{
+			RegisteredClaims: jwt.RegisteredClaims{
+				Subject:   "5cf37266-3473-4006-984f-9325122678b7",
+				ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(time.Hour)),
+			},
+			Roles: []string{auth.RoleUser},
+		}
+
+		for testID, kid := range []string{"es256", "es384", "eddsa"} {
+			t.Logf("\tTest %d:\tWhen signing with the %s key.", testID, kid)
+			{
+				a, err := auth.NewWithSigners(kid, ks, "ES256", "ES384", "EdDSA")
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to create an authenticator: %v", failed, testID, err)
+				}
+
+				token, err := a.GenerateToken(claims)
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to generate a JWT: %v", failed, testID, err)
+				}
+
+				if _, err := a.ValidateToken(token); err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to parse the claims: %v", failed, testID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould be able to parse the claims.", success, testID)
+			}
+		}
+
+		testID := 3
+		t.Logf("\tTest %d:\tWhen the token algorithm is not allowed.", testID)
+		{
+			signer, err := auth.NewWithSigners("es256", ks)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create an authenticator: %v", failed, testID, err)
+			}
+			verifier, err := auth.NewWithSigners("eddsa", ks)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create an authenticator: %v", failed, testID, err)
+			}
+
+			token, _ := signer.GenerateToken(claims)
+			if _, err := verifier.ValidateToken(token); err == nil {
+				t.Fatalf("\t%s\tTest %d:\tShould reject the token.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould reject the token.", success, testID)
+		}
+	}
+}
+
+func Test_OpaqueSigners(t *testing.T) {
+	t.Log("Given the need to sign tokens with keys held in a KMS or HSM.")
+	{
+		rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+		ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		ec521Key, _ := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+
+		ks := keystore.New()
+		for kid, key := range map[string]crypto.Signer{"rs256": rsaKey, "es256": ecKey, "es512": ec521Key} {
+			if err := ks.AddSigner(opaqueSigner{key}, kid); err != nil {
+				t.Fatalf("\t%s\tShould be able to add a %s key: %v", failed, kid, err)
+			}
+		}
+
+		claims := auth.Claims{
+			RegisteredClaims: jwt.RegisteredClaims{
+				Subject:   "5cf37266-3473-4006-984f-9325122678b7",
+				ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(time.Hour)),
+			},
+			Roles: []string{auth.RoleUser},
+		}
+
+		for testID, kid := range []string{"rs256", "es256", "es512"} {
+			t.Logf("\tTest %d:\tWhen signing with the opaque %s key.", testID, kid)
+			{
+				a, err := auth.NewWithSigners(kid, ks, "RS256", "ES256", "ES512")
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to create an authenticator: %v", failed, testID, err)
+				}
+
+				token, err := a.GenerateToken(claims)
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to generate a JWT: %v", failed, testID, err)
+				}
+
+				if _, err := a.ValidateToken(token); err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to parse the claims: %v", failed, testID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould be able to parse the claims.", success, testID)
+			}
+		}
+	}
+}
+
+func Test_HMAC(t *testing.T) {
+	t.Log("Given the need to sign tokens with shared secrets.")
+	{
+		ss := keystore.NewSecrets()
+		ss.Add([]byte("0123456789abcdef0123456789abcdef"), "v1")
+
+		a, err := auth.NewHMAC("v1", ss)
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to create an authenticator: %v", failed, err)
+		}
+
+		claims := auth.Claims{
+			RegisteredClaims: jwt.RegisteredClaims{
+				Subject:   "5cf37266-3473-4006-984f-9325122678b7",
+				ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(time.Hour)),
+			},
+			Roles: []string{auth.RoleUser},
+		}
+
+		testID := 0
+		t.Logf("\tTest %d:\tWhen the secret is rotated.", testID)
+		{
+			oldToken, _ := a.GenerateToken(claims)
+
+			ss.Add([]byte("fedcba9876543210fedcba9876543210"), "v2")
+			if err := a.Rotate("v2"); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to rotate the secret: %v", failed, testID, err)
+			}
+			newToken, _ := a.GenerateToken(claims)
+
+			for _, token := range []string{oldToken, newToken} {
+				if _, err := a.ValidateToken(token); err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to parse the claims: %v", failed, testID, err)
+				}
+			}
+			t.Logf("\t%s\tTest %d:\tShould accept tokens signed before and after rotation.", success, testID)
+
+			ss.Remove("v1")
+			if _, err := a.ValidateToken(oldToken); err == nil {
+				t.Fatalf("\t%s\tTest %d:\tShould reject tokens signed with a removed secret.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould reject tokens signed with a removed secret.", success, testID)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tWhen mixing symmetric and asymmetric tokens.", testID)
+		{
+			privateKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+			rsaAuth, err := auth.New("v2", &keyStore{pk: privateKey})
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create an authenticator: %v", failed, testID, err)
+			}
+
+			rsaToken, _ := rsaAuth.GenerateToken(claims)
+			if _, err := a.ValidateToken(rsaToken); err == nil {
+				t.Fatalf("\t%s\tTest %d:\tShould reject RS256 tokens with HMAC.", failed, testID)
+			}
+			hmacToken, _ := a.GenerateToken(claims)
+			if _, err := rsaAuth.ValidateToken(hmacToken); err == nil {
+				t.Fatalf("\t%s\tTest %d:\tShould reject HS256 tokens with RSA.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould reject tokens of the other kind.", success, testID)
+		}
+	}
+}
+
+func Test_Validation(t *testing.T) {
+	t.Log("Given the need to validate the standard claims of a token.")
+	{
+		privateKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+		a, err := auth.New("kid", &keyStore{pk: privateKey})
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to create an authenticator: %v", failed, err)
+		}
+		a.SetValidation(auth.Validation{
+			Issuers:        []string{"seed project"},
+			Audience:       "greeter",
+			Leeway:         time.Minute,
+			MaxAge:         time.Hour,
+			RequiredClaims: []string{"sub"},
+		})
+
+		now := time.Now().UTC()
+		valid := jwt.RegisteredClaims{
+			Issuer:    "seed project",
+			Subject:   "5cf37266-3473-4006-984f-9325122678b7",
+			Audience:  jwt.ClaimStrings{"greeter"},
+			ExpiresAt: jwt.NewNumericDate(now.Add(-30 * time.Second)),
+			IssuedAt:  jwt.NewNumericDate(now.Add(-time.Minute)),
+		}
+
+		ttable := []struct {
+			TestTitle string
+			Modify    func(c *jwt.RegisteredClaims)
+			Expected  error
+		}{
+			{"When expired within the leeway", func(c *jwt.RegisteredClaims) {}, nil},
+			{"When expired", func(c *jwt.RegisteredClaims) { c.ExpiresAt = jwt.NewNumericDate(now.Add(-2 * time.Minute)) }, auth.ErrTokenExpired},
+			{"When issued too long ago", func(c *jwt.RegisteredClaims) { c.IssuedAt = jwt.NewNumericDate(now.Add(-2 * time.Hour)) }, auth.ErrTokenTooOld},
+			{"When issued by another issuer", func(c *jwt.RegisteredClaims) { c.Issuer = "other" }, auth.ErrTokenInvalidIssuer},
+			{"When issued for another audience", func(c *jwt.RegisteredClaims) { c.Audience = jwt.ClaimStrings{"other"} }, auth.ErrTokenInvalidAudience},
+			{"When missing a required claim", func(c *jwt.RegisteredClaims) { c.Subject = "" }, auth.ErrTokenMissingClaim},
+		}
+
+		for testID, td := range ttable {
+			t.Logf("\tTest %d:\t%s.", testID, td.TestTitle)
+			{
+				rc := valid
+				td.Modify(&rc)
+				token, err := a.GenerateToken(auth.Claims{RegisteredClaims: rc})
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to generate a JWT: %v", failed, testID, err)
+				}
+
+				if _, err := a.ValidateToken(token); !errors.Is(err, td.Expected) {
+					t.Fatalf("\t%s\tTest %d:\tShould return %v : %v", failed, testID, td.Expected, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould return %v.", success, testID, td.Expected)
+			}
+		}
+
+		testID := len(ttable)
+		t.Logf("\tTest %d:\tWhen the token is malformed.", testID)
+		{
+			if _, err := a.ValidateToken("not.a.token"); !errors.Is(err, auth.ErrTokenMalformed) {
+				t.Fatalf("\t%s\tTest %d:\tShould return %v : %v", failed, testID, auth.ErrTokenMalformed, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould return %v.", success, testID, auth.ErrTokenMalformed)
+		}
+	}
+}
+
+func Test_Permissions(t *testing.T) {
+	t.Log("Given the need to authorize by permissions.")
+	{
+		p := auth.NewPermissions().
+			Grant(auth.RoleUser, "greeter:read").
+			Grant(auth.RoleAdmin, "greeter:*", "users:write").
+			Imply(auth.RoleAdmin, auth.RoleUser)
+
+		ttable := []struct {
+			TestTitle string
+			Roles     []string
+			Req       auth.Requirement
+			Allowed   bool
+		}{
+			{"When a user reads", []string{auth.RoleUser}, auth.Requirement{AllOf: []string{"greeter:read"}}, true},
+			{"When a user writes", []string{auth.RoleUser}, auth.Requirement{AllOf: []string{"greeter:read", "greeter:write"}}, false},
+			{"When an admin writes", []string{auth.RoleAdmin}, auth.Requirement{AllOf: []string{"greeter:write"}}, true},
+			{"When a user needs any of", []string{auth.RoleUser}, auth.Requirement{AnyOf: []string{"users:write", "greeter:read"}}, true},
+			{"When a caller has no roles", nil, auth.Requirement{AnyOf: []string{"greeter:read"}}, false},
+		}
+
+		for testID, td := range ttable {
+			t.Logf("\tTest %d:\t%s.", testID, td.TestTitle)
+			{
+				d := p.Check(p.ExpandRoles(td.Roles), td.Req)
+				if d.Allowed != td.Allowed {
+					t.Fatalf("\t%s\tTest %d:\tShould be allowed %v : %+v", failed, testID, td.Allowed, d)
+				}
+				if !d.Allowed && d.Reason == "" {
+					t.Fatalf("\t%s\tTest %d:\tShould explain the denial.", failed, testID)
+				}
+				t.Logf("\t%s\tTest %d:\tShould be allowed %v.", success, testID, td.Allowed)
+			}
+		}
+
+		testID := len(ttable)
+		t.Logf("\tTest %d:\tWhen roles imply other roles.", testID)
+		{
+			roles := p.ExpandRoles([]string{auth.RoleAdmin})
+			if len(roles) != 2 || roles[1] != auth.RoleUser {
+				t.Fatalf("\t%s\tTest %d:\tShould include implied roles : %v", failed, testID, roles)
+			}
+			t.Logf("\t%s\tTest %d:\tShould include implied roles.", success, testID)
+		}
+	}
+}
+
+func Test_Impersonation(t *testing.T) {
+	t.Log("Given the need to act on behalf of another user.")
+	{
+		privateKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+		a, err := auth.New("kid", &keyStore{pk: privateKey})
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to create an authenticator: %v", failed, err)
+		}
+
+		exp := jwt.NewNumericDate(time.Now().Add(time.Hour).Truncate(time.Second))
+		support := auth.Claims{
+			RegisteredClaims: jwt.RegisteredClaims{Subject: "support", ExpiresAt: exp},
+			Roles:            []string{auth.RoleImpersonator},
+		}
+		user := auth.Claims{
+			RegisteredClaims: jwt.RegisteredClaims{Subject: "user", ID: "user-jti"},
+			Roles:            []string{auth.RoleUser},
+			AMR:              []string{"pwd", "otp"},
+		}
+		admin := auth.Claims{
+			RegisteredClaims: jwt.RegisteredClaims{Subject: "admin"},
+			Roles:            []string{auth.RoleUser},
+			TenantRoles:      map[string][]string{"acme": {auth.RoleAdmin}},
+		}
+
+		t.Logf("\tTest %d:\tWhen an impersonator acts as a user.", 0)
+		{
+			token, err := a.GenerateImpersonationToken(support, user)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to generate a token: %v", failed, 0, err)
+			}
+			claims, err := a.ValidateToken(token)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to validate the token: %v", failed, 0, err)
+			}
+			if claims.Subject != "user" || claims.Actor() != "support" || !claims.Impersonated() {
+				t.Fatalf("\t%s\tTest %d:\tShould carry both identities: %+v", failed, 0, claims)
+			}
+			if claims.ExpiresAt == nil || !claims.ExpiresAt.Equal(exp.Time) {
+				t.Fatalf("\t%s\tTest %d:\tShould not outlive the actor: %v", failed, 0, claims.ExpiresAt)
+			}
+			t.Logf("\t%s\tTest %d:\tShould carry both identities.", success, 0)
+
+			if claims.ID == "" || claims.ID == user.ID {
+				t.Fatalf("\t%s\tTest %d:\tShould have its own id : %q", failed, 0, claims.ID)
+			}
+			if claims.SteppedUp() || len(claims.AMR) != 0 {
+				t.Fatalf("\t%s\tTest %d:\tShould not carry the authentication methods of the subject : %v", failed, 0, claims.AMR)
+			}
+			t.Logf("\t%s\tTest %d:\tShould have its own id and no authentication methods.", success, 0)
+		}
+
+		ttable := []struct {
+			TestTitle string
+			Actor     auth.Claims
+			Subject   auth.Claims
+		}{
+			{"When the actor is missing the role", user, support},
+			{"When the subject is an impersonator", support, support},
+			{"When the subject is an admin in a tenant", support, admin},
+			{"When the actor is already impersonating", auth.Claims{
+				RegisteredClaims: jwt.RegisteredClaims{Subject: "user"},
+				Roles:            []string{auth.RoleImpersonator},
+				Act:              &auth.Actor{Subject: "support"},
+			}, user},
+		}
+
+		for i, td := range ttable {
+			testID := i + 1
+			t.Logf("\tTest %d:\t%s.", testID, td.TestTitle)
+			{
+				if _, err := a.GenerateImpersonationToken(td.Actor, td.Subject); !errors.Is(err, auth.ErrForbidden) {
+					t.Fatalf("\t%s\tTest %d:\tShould be forbidden: %v", failed, testID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould be forbidden.", success, testID)
+			}
+		}
+
+		testID := len(ttable) + 1
+		t.Logf("\tTest %d:\tWhen admins are not configured as privileged.", testID)
+		{
+			a.SetPrivilegedRoles()
+			if _, err := a.GenerateImpersonationToken(support, admin); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to impersonate the admin: %v", failed, testID, err)
+			}
+			if _, err := a.GenerateImpersonationToken(support, support); !errors.Is(err, auth.ErrForbidden) {
+				t.Fatalf("\t%s\tTest %d:\tShould still refuse impersonators: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould only refuse impersonators.", success, testID)
+		}
+	}
+}
+
 // =============================================================================
 
 type keyStore struct {
@@ -92,3 +478,16 @@ func (ks *keyStore) PrivateKey(kid string) (*rsa.PrivateKey, error) {
 func (ks *keyStore) PublicKey(kid string) (*rsa.PublicKey, error) {
 	return &ks.pk.PublicKey, nil
 }
+
+// opaqueSigner hides the type of its key, like signers backed by a KMS.
+type opaqueSigner struct {
+	key crypto.Signer
+}
+
+func (s opaqueSigner) Public() crypto.PublicKey {
+	return s.key.Public()
+}
+
+func (s opaqueSigner) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	return s.key.Sign(rand, digest, opts)
+}
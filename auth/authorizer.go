@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// ErrAmbiguousRequest is returned by DecodeRequest for request bodies with
+// members whose names only differ in case. encoding/json matches struct
+// fields case-insensitively, so an authorizer could otherwise check a
+// different member than the handler acts on.
+var ErrAmbiguousRequest = errors.New("request has duplicate members")
+
+// AuthzInput is the information an Authorizer decides on.
+type AuthzInput struct {
+	// Route is the path of the rpc procedure called.
+	Route string
+	// Claims of the caller. Empty for anonymous callers.
+	Claims Claims
+	// Request is the JSON request body as decoded by DecodeRequest, or nil
+	// if the body is not a JSON object. Use Field to read its members.
+	Request map[string]any
+}
+
+// Authorizer declares a method set of behavior for authorizing each rpc
+// based on attributes of the caller and the request, such as ownership or
+// tenant checks.
+type Authorizer interface {
+	Authorize(ctx context.Context, in AuthzInput) (Decision, error)
+}
+
+// DecodeRequest decodes a JSON object request body. It returns
+// ErrAmbiguousRequest if any object in the body has members whose names
+// only differ in case.
+func DecodeRequest(b []byte) (map[string]any, error) {
+	var req map[string]any
+	if err := json.Unmarshal(b, &req); err != nil {
+		return nil, err
+	}
+	if err := checkMembers(json.NewDecoder(bytes.NewReader(b))); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// Field returns the member of a decoded request object matching name the
+// way encoding/json matches struct fields, ignoring case.
+func Field(m map[string]any, name string) (any, bool) {
+	if v, ok := m[name]; ok {
+		return v, true
+	}
+	for k, v := range m {
+		if strings.EqualFold(k, name) {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// checkMembers reads the next JSON value and returns ErrAmbiguousRequest if
+// it holds an object with members whose names only differ in case.
+func checkMembers(dec *json.Decoder) error {
+	t, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	d, ok := t.(json.Delim)
+	if !ok {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	for dec.More() {
+		if d == '{' {
+			t, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			name := strings.ToLower(strings.ToUpper(t.(string)))
+			if seen[name] {
+				return ErrAmbiguousRequest
+			}
+			seen[name] = true
+		}
+		if err := checkMembers(dec); err != nil {
+			return err
+		}
+	}
+
+	// Read the closing delimiter.
+	_, err = dec.Token()
+	return err
+}
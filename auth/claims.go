@@ -16,12 +16,45 @@ import (
 const (
 	RoleAdmin = "ADMIN"
 	RoleUser  = "USER"
+	// RoleImpersonator allows acting as another user.
+	RoleImpersonator = "IMPERSONATOR"
+)
+
+// These are the expected values for Claims.AMR, as registered by RFC 8176.
+const (
+	AMRPassword = "pwd"
+	AMROTP      = "otp"
+	AMRMFA      = "mfa"
 )
 
 // Claims represents the authorization claims transmitted via a JWT.
 type Claims struct {
 	jwt.RegisteredClaims
 	Roles []string `json:"roles"`
+	// Tenant is the tenant the caller acts in.
+	Tenant string `json:"tenant,omitempty"`
+	// TenantRoles are roles the caller only has within a tenant.
+	TenantRoles map[string][]string `json:"tenant_roles,omitempty"`
+	// Act identifies who is acting on behalf of the subject when the token
+	// is an impersonation token, as described in RFC 8693.
+	Act *Actor `json:"act,omitempty"`
+	// AMR lists the methods used to authenticate the subject.
+	AMR []string `json:"amr,omitempty"`
+
+	// raw is the payload of the validated token, kept so application
+	// claims can be recovered with ClaimsAs.
+	raw []byte
+}
+
+// RolesFor returns the roles of the claims within the tenant.
+func (c Claims) RolesFor(tenant string) []string {
+	scoped := c.TenantRoles[tenant]
+	if tenant == "" || len(scoped) == 0 {
+		return c.Roles
+	}
+	roles := make([]string, 0, len(c.Roles)+len(scoped))
+	roles = append(roles, c.Roles...)
+	return append(roles, scoped...)
 }
 
 // Authorized returns true if the claims has at least one of the provided roles.
@@ -36,12 +69,28 @@ func (c Claims) Authorized(roles ...string) bool {
 	return false
 }
 
+// SteppedUp returns true if the subject authenticated with a second factor.
+func (c Claims) SteppedUp() bool {
+	for _, m := range c.AMR {
+		if m == AMRMFA {
+			return true
+		}
+	}
+	return false
+}
+
 // ctxKey represents the type of value for the context key.
 type ctxKey int
 
 // key is used to store/retrieve a Claims value from a context.Context.
 const key ctxKey = 1
 
+// errKey is used to store/retrieve an authentication error from a context.Context.
+const errKey ctxKey = 2
+
+// schemeKey is used to store/retrieve the authentication scheme from a context.Context.
+const schemeKey ctxKey = 3
+
 // SetClaims stores the claims in the context.
 func SetClaims(ctx context.Context, claims Claims) context.Context {
 	return context.WithValue(ctx, key, claims)
@@ -55,3 +104,28 @@ func GetClaims(ctx context.Context) (Claims, error) {
 	}
 	return v, nil
 }
+
+// SetAuthError stores the reason the credentials of the request were
+// rejected in the context.
+func SetAuthError(ctx context.Context, err error) context.Context {
+	return context.WithValue(ctx, errKey, err)
+}
+
+// GetAuthError returns the reason the credentials of the request were
+// rejected, or nil if none were rejected.
+func GetAuthError(ctx context.Context) error {
+	err, _ := ctx.Value(errKey).(error)
+	return err
+}
+
+// SetScheme stores the scheme that authenticated the request in the context.
+func SetScheme(ctx context.Context, scheme string) context.Context {
+	return context.WithValue(ctx, schemeKey, scheme)
+}
+
+// GetScheme returns the scheme that authenticated the request, or an empty
+// string if the request was not authenticated.
+func GetScheme(ctx context.Context) string {
+	scheme, _ := ctx.Value(schemeKey).(string)
+	return scheme
+}
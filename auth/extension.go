@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+)
+
+// Extension is implemented by application claims types that embed Claims
+// to carry extra claims such as an email or feature flags.
+//
+//	type AppClaims struct {
+//		auth.Claims
+//		Email string `json:"email"`
+//	}
+type Extension interface {
+	jwt.Claims
+	Base() Claims
+}
+
+// Base returns the claims, so that types embedding Claims implement
+// Extension.
+func (c Claims) Base() Claims {
+	return c
+}
+
+// GenerateExtendedToken generates a signed JWT token string representing
+// the application claims. A unique token id (jti), and the issued at time
+// (iat), are assigned when the claims do not have them.
+func GenerateExtendedToken[T Extension](a *Auth, claims T) (string, error) {
+	if a.signingKey == nil {
+		return "", ErrVerifyOnly
+	}
+
+	base := claims.Base()
+	if base.ID != "" && base.IssuedAt != nil {
+		return a.sign(claims)
+	}
+
+	// The embedded claims can not be set through T, so the id and issued at
+	// time are added to the encoded claims instead.
+	b, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("encoding claims: %w", err)
+	}
+	var m jwt.MapClaims
+	if err := json.Unmarshal(b, &m); err != nil {
+		return "", fmt.Errorf("encoding claims: %w", err)
+	}
+	if base.ID == "" {
+		m["jti"] = uuid.NewString()
+	}
+	if base.IssuedAt == nil {
+		m["iat"] = jwt.NewNumericDate(time.Now())
+	}
+
+	return a.sign(m)
+}
+
+// ValidateExtendedToken validates the token like ValidateToken and returns
+// the application claims it carries.
+func ValidateExtendedToken[T Extension](a *Auth, tokenStr string) (T, error) {
+	claims, err := a.ValidateToken(tokenStr)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return ClaimsAs[T](claims)
+}
+
+// ClaimsAs returns the application claims of a validated token. Claims that
+// did not come from a token only populate the embedded Claims of T.
+func ClaimsAs[T Extension](c Claims) (T, error) {
+	var claims T
+
+	raw := c.raw
+	if raw == nil {
+		b, err := json.Marshal(c)
+		if err != nil {
+			return claims, fmt.Errorf("encoding claims: %w", err)
+		}
+		raw = b
+	}
+
+	if err := json.Unmarshal(raw, &claims); err != nil {
+		return claims, fmt.Errorf("decoding claims: %w", err)
+	}
+
+	return claims, nil
+}
+
+// GetExtendedClaims returns the application claims from the context.
+func GetExtendedClaims[T Extension](ctx context.Context) (T, error) {
+	c, err := GetClaims(ctx)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return ClaimsAs[T](c)
+}
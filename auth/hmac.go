@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// MinSecretSize is the minimum size in bytes of an HMAC secret.
+const MinSecretSize = 32
+
+// SecretLookup declares a method set of behavior for looking up
+// symmetric secrets for HMAC signed JWT use.
+type SecretLookup interface {
+	Secret(kid string) ([]byte, error)
+}
+
+// NewHMAC creates an Auth that signs and verifies tokens with HS256 using
+// shared secrets. An HMAC Auth only accepts HS256 tokens and asymmetric Auths
+// never accept them, so secrets and public keys can not be confused.
+func NewHMAC(activeKID string, secrets SecretLookup) (*Auth, error) {
+	method := jwt.SigningMethodHS256
+
+	lookup := func(kid string) ([]byte, error) {
+		secret, err := secrets.Secret(kid)
+		if err != nil {
+			return nil, err
+		}
+		if len(secret) < MinSecretSize {
+			return nil, fmt.Errorf("secret must be at least %d bytes", MinSecretSize)
+		}
+		return secret, nil
+	}
+
+	signingKey := func(kid string) (any, jwt.SigningMethod, error) {
+		secret, err := lookup(kid)
+		if err != nil {
+			return nil, nil, err
+		}
+		return secret, method, nil
+	}
+
+	keyFunc := func(t *jwt.Token) (any, error) {
+		if t.Method != method {
+			return nil, errors.New("token algorithm must be HS256")
+		}
+		kid, err := tokenKID(t)
+		if err != nil {
+			return nil, err
+		}
+		return lookup(kid)
+	}
+
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{method.Alg()}), jwt.WithoutClaimsValidation())
+
+	return newAuth(activeKID, signingKey, keyFunc, parser)
+}
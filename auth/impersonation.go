@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"fmt"
+)
+
+// Actor is the party acting on behalf of the subject of a token.
+type Actor struct {
+	Subject string `json:"sub"`
+}
+
+// Actor returns the subject of whoever is making the request, which is the
+// actor for impersonation tokens and the subject otherwise.
+func (c Claims) Actor() string {
+	if c.Act != nil {
+		return c.Act.Subject
+	}
+	return c.Subject
+}
+
+// Impersonated returns true if the claims were issued to an actor acting on
+// behalf of the subject.
+func (c Claims) Impersonated() bool {
+	return c.Act != nil
+}
+
+// SetPrivilegedRoles configures the roles of subjects that can not be
+// impersonated, in any tenant. RoleImpersonator can never be impersonated.
+// It must be called before the Auth is used.
+// Default: ADMIN
+func (a *Auth) SetPrivilegedRoles(roles ...string) {
+	a.privileged = append([]string{}, roles...)
+}
+
+// GenerateImpersonationToken generates a token for the subject claims that
+// records the actor as the party acting on their behalf. The actor must have
+// RoleImpersonator and can not be impersonating someone already, and the
+// subject can not hold a privileged role. The token gets its own id, does
+// not carry the authentication methods of the subject and does not outlive
+// the actor's claims.
+func (a *Auth) GenerateImpersonationToken(actor Claims, subject Claims) (string, error) {
+	privileged := []string{RoleImpersonator}
+	if a.privileged == nil {
+		privileged = append(privileged, RoleAdmin)
+	}
+	privileged = append(privileged, a.privileged...)
+
+	switch {
+	case !actor.Authorized(RoleImpersonator):
+		return "", fmt.Errorf("actor is missing role %s: %w", RoleImpersonator, ErrForbidden)
+	case actor.Impersonated():
+		return "", fmt.Errorf("actor is impersonating %s: %w", actor.Subject, ErrForbidden)
+	case actor.Subject == "" || subject.Subject == "":
+		return "", fmt.Errorf("actor and subject must be identified: %w", ErrForbidden)
+	}
+	for _, roles := range subject.allRoles() {
+		for _, role := range roles {
+			for _, p := range privileged {
+				if role == p {
+					return "", fmt.Errorf("subject has privileged role %s: %w", role, ErrForbidden)
+				}
+			}
+		}
+	}
+
+	subject.ID = ""
+	subject.IssuedAt = nil
+	subject.AMR = nil
+
+	if actor.ExpiresAt != nil && (subject.ExpiresAt == nil || subject.ExpiresAt.After(actor.ExpiresAt.Time)) {
+		subject.ExpiresAt = actor.ExpiresAt
+	}
+	subject.Act = &Actor{Subject: actor.Subject}
+
+	return a.GenerateToken(subject)
+}
+
+// allRoles returns the roles of the claims in and outside of any tenant.
+func (c Claims) allRoles() [][]string {
+	roles := [][]string{c.Roles}
+	for _, scoped := range c.TenantRoles {
+		roles = append(roles, scoped)
+	}
+	return roles
+}
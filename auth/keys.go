@@ -0,0 +1,161 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// PublicKeyLookup declares a method set of behavior for looking up
+// verifying keys, along with the algorithm each kid is used with.
+type PublicKeyLookup interface {
+	VerifyingKey(kid string) (crypto.PublicKey, string, error)
+}
+
+// SignerLookup declares a method set of behavior for looking up signing
+// and verifying keys, along with the algorithm each kid is used with.
+// Signers only need to implement crypto.Signer, so keys may be held in a
+// KMS or HSM.
+type SignerLookup interface {
+	PublicKeyLookup
+	SigningKey(kid string) (crypto.Signer, string, error)
+}
+
+// These are the supported asymmetric signing algorithms.
+var asymmetricAlgs = map[string]bool{
+	"RS256": true,
+	"RS384": true,
+	"RS512": true,
+	"ES256": true,
+	"ES384": true,
+	"ES512": true,
+	"EdDSA": true,
+}
+
+// AlgorithmForKey returns the default signing algorithm for a public key.
+func AlgorithmForKey(pub crypto.PublicKey) (string, error) {
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		return "RS256", nil
+	case *ecdsa.PublicKey:
+		switch k.Curve {
+		case elliptic.P256():
+			return "ES256", nil
+		case elliptic.P384():
+			return "ES384", nil
+		case elliptic.P521():
+			return "ES512", nil
+		}
+		return "", errors.New("unsupported ecdsa curve")
+	case ed25519.PublicKey:
+		return "EdDSA", nil
+	}
+	return "", fmt.Errorf("unsupported key type %T", pub)
+}
+
+// checkKey verifies the public key can be used with the algorithm.
+func checkKey(alg string, pub crypto.PublicKey) error {
+	if alg == "RS384" || alg == "RS512" {
+		alg = "RS256"
+	}
+	keyAlg, err := AlgorithmForKey(pub)
+	if err != nil {
+		return err
+	}
+	if keyAlg != alg {
+		return fmt.Errorf("key type %T can not be used with %s", pub, alg)
+	}
+	return nil
+}
+
+// rsaLookup adapts a KeyLookup to the SignerLookup interface.
+type rsaLookup struct {
+	KeyLookup
+}
+
+// SigningKey implements the SignerLookup interface.
+func (l rsaLookup) SigningKey(kid string) (crypto.Signer, string, error) {
+	pk, err := l.PrivateKey(kid)
+	if err != nil {
+		return nil, "", err
+	}
+	return pk, "RS256", nil
+}
+
+// VerifyingKey implements the SignerLookup interface.
+func (l rsaLookup) VerifyingKey(kid string) (crypto.PublicKey, string, error) {
+	pk, err := l.PublicKey(kid)
+	if err != nil {
+		return nil, "", err
+	}
+	return pk, "RS256", nil
+}
+
+// signerMethod signs tokens through crypto.Signer, so keys that never leave
+// a KMS or HSM can sign. jwt only signs RS* and ES* tokens with in-memory
+// keys. Verification is left to the jwt method.
+type signerMethod struct {
+	jwt.SigningMethod
+	hash crypto.Hash
+	// size of each of r and s for ES* signatures.
+	size int
+}
+
+// signingMethod returns the method used to sign tokens with the algorithm.
+func signingMethod(alg string) jwt.SigningMethod {
+	switch alg {
+	case "RS256":
+		return signerMethod{SigningMethod: jwt.SigningMethodRS256, hash: crypto.SHA256}
+	case "RS384":
+		return signerMethod{SigningMethod: jwt.SigningMethodRS384, hash: crypto.SHA384}
+	case "RS512":
+		return signerMethod{SigningMethod: jwt.SigningMethodRS512, hash: crypto.SHA512}
+	case "ES256":
+		return signerMethod{SigningMethod: jwt.SigningMethodES256, hash: crypto.SHA256, size: 32}
+	case "ES384":
+		return signerMethod{SigningMethod: jwt.SigningMethodES384, hash: crypto.SHA384, size: 48}
+	case "ES512":
+		return signerMethod{SigningMethod: jwt.SigningMethodES512, hash: crypto.SHA512, size: 66}
+	}
+	return jwt.GetSigningMethod(alg)
+}
+
+// Sign implements the jwt.SigningMethod interface.
+func (m signerMethod) Sign(signingString string, key any) (string, error) {
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return "", jwt.ErrInvalidKeyType
+	}
+
+	h := m.hash.New()
+	h.Write([]byte(signingString))
+	sig, err := signer.Sign(rand.Reader, h.Sum(nil), m.hash)
+	if err != nil {
+		return "", fmt.Errorf("signing token: %w", err)
+	}
+
+	// crypto.Signer returns ASN.1 ECDSA signatures, JWS uses r || s.
+	if m.size > 0 {
+		var rs struct{ R, S *big.Int }
+		if rest, err := asn1.Unmarshal(sig, &rs); err != nil || len(rest) > 0 {
+			return "", errors.New("signer returned a malformed ecdsa signature")
+		}
+		if rs.R.Sign() <= 0 || rs.S.Sign() <= 0 || rs.R.BitLen() > m.size*8 || rs.S.BitLen() > m.size*8 {
+			return "", errors.New("signer returned an invalid ecdsa signature")
+		}
+		sig = make([]byte, 2*m.size)
+		rs.R.FillBytes(sig[:m.size])
+		rs.S.FillBytes(sig[m.size:])
+	}
+
+	return jwt.EncodeSegment(sig), nil
+}
@@ -0,0 +1,204 @@
+// Package password hashes and verifies passwords, and checks user
+// credentials against a pluggable store so login endpoints can mint tokens.
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// These are the supported hashing algorithms.
+const (
+	Argon2id = "argon2id"
+	Bcrypt   = "bcrypt"
+)
+
+// Set of errors returned when verifying a password.
+var (
+	ErrMismatch     = errors.New("password does not match")
+	ErrInvalidHash  = errors.New("hash is not in a supported format")
+	ErrIncompatible = errors.New("hash was created with an incompatible version of argon2")
+)
+
+// Argon2Params are the parameters of argon2id hashes.
+type Argon2Params struct {
+	// Memory in KiB.
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// Hasher hashes passwords with the configured algorithm and parameters.
+// Hashes encode their parameters, so hashes made with other parameters can
+// still be verified while parameters are raised over time.
+type Hasher struct {
+	// Algorithm used for new hashes. Default: argon2id
+	Algorithm string
+	Argon2    Argon2Params
+	// BcryptCost is the cost of bcrypt hashes. Default: bcrypt.DefaultCost
+	BcryptCost int
+}
+
+// DefaultHasher returns a Hasher using argon2id with the parameters
+// recommended by RFC 9106 for memory constrained environments.
+func DefaultHasher() Hasher {
+	return Hasher{
+		Algorithm: Argon2id,
+		Argon2: Argon2Params{
+			Memory:      64 * 1024,
+			Iterations:  3,
+			Parallelism: 4,
+			SaltLength:  16,
+			KeyLength:   32,
+		},
+		BcryptCost: bcrypt.DefaultCost,
+	}
+}
+
+// Hash returns the encoded hash of the password.
+func (h Hasher) Hash(password string) (string, error) {
+	switch h.Algorithm {
+	case Bcrypt:
+		b, err := bcrypt.GenerateFromPassword([]byte(password), h.bcryptCost())
+		if err != nil {
+			return "", fmt.Errorf("hashing password: %w", err)
+		}
+		return string(b), nil
+
+	case Argon2id, "":
+		p := h.argon2Params()
+		salt := make([]byte, p.SaltLength)
+		if _, err := rand.Read(salt); err != nil {
+			return "", fmt.Errorf("generating salt: %w", err)
+		}
+		key := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+		return encodeArgon2(p, salt, key), nil
+	}
+
+	return "", fmt.Errorf("algorithm %s is not supported", h.Algorithm)
+}
+
+// Verify checks the password against the encoded hash in constant time. It
+// returns ErrMismatch if the password does not match, and true if the
+// password should be hashed again because the hash does not use the
+// algorithm or parameters of the Hasher.
+func (h Hasher) Verify(encoded, password string) (bool, error) {
+	if strings.HasPrefix(encoded, "$argon2id$") {
+		p, salt, key, err := decodeArgon2(encoded)
+		if err != nil {
+			return false, err
+		}
+		other := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, uint32(len(key)))
+		if subtle.ConstantTimeCompare(key, other) != 1 {
+			return false, ErrMismatch
+		}
+		return h.algorithm() != Argon2id || p != h.argon2Params(), nil
+	}
+
+	cost, err := bcrypt.Cost([]byte(encoded))
+	if err != nil {
+		return false, ErrInvalidHash
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password)); err != nil {
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, ErrMismatch
+		}
+		return false, fmt.Errorf("verifying password: %w", err)
+	}
+	return h.algorithm() != Bcrypt || cost != h.bcryptCost(), nil
+}
+
+// algorithm returns the algorithm for new hashes.
+func (h Hasher) algorithm() string {
+	if h.Algorithm == "" {
+		return Argon2id
+	}
+	return h.Algorithm
+}
+
+// argon2Params returns the argon2id parameters, using the defaults for any
+// that are not set.
+func (h Hasher) argon2Params() Argon2Params {
+	p := h.Argon2
+	d := DefaultHasher().Argon2
+	if p.Memory == 0 {
+		p.Memory = d.Memory
+	}
+	if p.Iterations == 0 {
+		p.Iterations = d.Iterations
+	}
+	if p.Parallelism == 0 {
+		p.Parallelism = d.Parallelism
+	}
+	if p.SaltLength == 0 {
+		p.SaltLength = d.SaltLength
+	}
+	if p.KeyLength == 0 {
+		p.KeyLength = d.KeyLength
+	}
+	return p
+}
+
+// bcryptCost returns the bcrypt cost, using the default if it is not set.
+func (h Hasher) bcryptCost() int {
+	if h.BcryptCost == 0 {
+		return bcrypt.DefaultCost
+	}
+	return h.BcryptCost
+}
+
+// encodeArgon2 encodes an argon2id hash in the PHC string format:
+// $argon2id$v=19$m=65536,t=3,p=4$<salt>$<key>
+func encodeArgon2(p Argon2Params, salt, key []byte) string {
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.Memory, p.Iterations, p.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	)
+}
+
+// decodeArgon2 decodes an argon2id hash in the PHC string format.
+func decodeArgon2(encoded string) (Argon2Params, []byte, []byte, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		return Argon2Params{}, nil, nil, ErrInvalidHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return Argon2Params{}, nil, nil, ErrInvalidHash
+	}
+	if version != argon2.Version {
+		return Argon2Params{}, nil, nil, ErrIncompatible
+	}
+
+	var p Argon2Params
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Iterations, &p.Parallelism); err != nil {
+		return Argon2Params{}, nil, nil, ErrInvalidHash
+	}
+	if p.Memory == 0 || p.Iterations == 0 || p.Parallelism == 0 {
+		return Argon2Params{}, nil, nil, ErrInvalidHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return Argon2Params{}, nil, nil, ErrInvalidHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return Argon2Params{}, nil, nil, ErrInvalidHash
+	}
+	p.SaltLength = uint32(len(salt))
+	p.KeyLength = uint32(len(key))
+
+	return p, salt, key, nil
+}
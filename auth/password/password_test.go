@@ -0,0 +1,146 @@
+package password_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gitamped/seed/auth"
+	"github.com/gitamped/seed/auth/password"
+	"github.com/gitamped/seed/keystore"
+	"github.com/gitamped/seed/tokens"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+// cheap keeps argon2id fast for tests.
+var cheap = password.Argon2Params{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func Test_Hasher(t *testing.T) {
+	t.Log("Given the need to hash passwords.")
+	{
+		ttable := []struct {
+			TestTitle string
+			Hasher    password.Hasher
+			Prefix    string
+		}{
+			{"When hashing with argon2id", password.Hasher{Algorithm: password.Argon2id, Argon2: cheap}, "$argon2id$v=19$m=1024,t=1,p=1$"},
+			{"When hashing with bcrypt", password.Hasher{Algorithm: password.Bcrypt, BcryptCost: 4}, "$2a$04$"},
+		}
+
+		for testID, td := range ttable {
+			t.Logf("\tTest %d:\t%s.", testID, td.TestTitle)
+			{
+				hash, err := td.Hasher.Hash("gophers")
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to hash : %v", failed, testID, err)
+				}
+				if !strings.HasPrefix(hash, td.Prefix) {
+					t.Fatalf("\t%s\tTest %d:\tShould encode the parameters : %s", failed, testID, hash)
+				}
+				t.Logf("\t%s\tTest %d:\tShould encode the parameters.", success, testID)
+
+				rehash, err := td.Hasher.Verify(hash, "gophers")
+				if err != nil || rehash {
+					t.Fatalf("\t%s\tTest %d:\tShould verify the password : %v %v", failed, testID, err, rehash)
+				}
+				if _, err := td.Hasher.Verify(hash, "gopher"); !errors.Is(err, password.ErrMismatch) {
+					t.Fatalf("\t%s\tTest %d:\tShould reject a wrong password : %v", failed, testID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould verify the password.", success, testID)
+			}
+		}
+
+		testID := len(ttable)
+		t.Logf("\tTest %d:\tWhen the parameters have changed.", testID)
+		{
+			old, _ := password.Hasher{Algorithm: password.Bcrypt, BcryptCost: 4}.Hash("gophers")
+			rehash, err := password.Hasher{Algorithm: password.Argon2id, Argon2: cheap}.Verify(old, "gophers")
+			if err != nil || !rehash {
+				t.Fatalf("\t%s\tTest %d:\tShould ask for a rehash : %v %v", failed, testID, err, rehash)
+			}
+			t.Logf("\t%s\tTest %d:\tShould ask for a rehash.", success, testID)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tWhen the hash is not supported.", testID)
+		{
+			if _, err := password.DefaultHasher().Verify("plaintext", "plaintext"); !errors.Is(err, password.ErrInvalidHash) {
+				t.Fatalf("\t%s\tTest %d:\tShould reject the hash : %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould reject the hash.", success, testID)
+		}
+	}
+}
+
+func Test_Login(t *testing.T) {
+	t.Log("Given the need to log users in with a password.")
+	{
+		old := password.Hasher{Algorithm: password.Bcrypt, BcryptCost: 4}
+		hash, _ := old.Hash("gophers")
+
+		store := password.NewMemoryStore()
+		store.Add("gopher", password.Credential{
+			Hash: hash,
+			Claims: auth.Claims{
+				RegisteredClaims: jwt.RegisteredClaims{Subject: "gopher"},
+				Roles:            []string{auth.RoleUser},
+			},
+		})
+
+		v, err := password.NewVerifier(password.Hasher{Algorithm: password.Argon2id, Argon2: cheap}, store)
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to create a verifier : %v", failed, err)
+		}
+
+		a, err := auth.NewHMAC("v1", keystore.NewSecretMap(map[string][]byte{"v1": []byte("0123456789abcdef0123456789abcdef")}))
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to create an authenticator : %v", failed, err)
+		}
+		ts := tokens.NewTokenServicer(a, v, tokens.NewMemoryStore(), tokens.Config{})
+
+		ttable := []struct {
+			TestTitle string
+			Username  string
+			Password  string
+			Success   bool
+		}{
+			{"When the password matches", "gopher", "gophers", true},
+			{"When the password does not match", "gopher", "gopher", false},
+			{"When the user does not exist", "nobody", "gophers", false},
+		}
+
+		for testID, td := range ttable {
+			t.Logf("\tTest %d:\t%s.", testID, td.TestTitle)
+			{
+				resp, err := ts.Login(context.Background(), tokens.LoginRequest{Username: td.Username, Password: td.Password})
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to log in : %v", failed, testID, err)
+				}
+				if (resp.AccessToken != "") != td.Success {
+					t.Fatalf("\t%s\tTest %d:\tShould receive a token %v : %+v", failed, testID, td.Success, resp)
+				}
+				if !td.Success && resp.Error != tokens.ErrInvalidCredentials.Error() {
+					t.Fatalf("\t%s\tTest %d:\tShould not tell why the credentials are invalid : %s", failed, testID, resp.Error)
+				}
+				t.Logf("\t%s\tTest %d:\tShould receive a token %v.", success, testID, td.Success)
+			}
+		}
+
+		testID := len(ttable)
+		t.Logf("\tTest %d:\tWhen the hash used old parameters.", testID)
+		{
+			c, _ := store.Credential(context.Background(), "gopher")
+			if !strings.HasPrefix(c.Hash, "$argon2id$") {
+				t.Fatalf("\t%s\tTest %d:\tShould rehash on login : %s", failed, testID, c.Hash)
+			}
+			t.Logf("\t%s\tTest %d:\tShould rehash on login.", success, testID)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package password
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/gitamped/seed/auth"
+	"github.com/gitamped/seed/tokens"
+)
+
+// ErrNotFound is returned by a CredentialStore when the user does not exist.
+var ErrNotFound = errors.New("user not found")
+
+// Credential is the password hash of a user and the claims to issue them.
+type Credential struct {
+	Hash   string
+	Claims auth.Claims
+}
+
+// CredentialStore declares a method set of behavior for looking up the
+// credentials of users.
+type CredentialStore interface {
+	Credential(ctx context.Context, username string) (Credential, error)
+	UpdateHash(ctx context.Context, username, hash string) error
+}
+
+// Verifier checks usernames and passwords against a credential store. It
+// implements the tokens.Authenticator and tokens.ClaimsLoader interfaces for
+// the TokenService, and the mid.CredentialStore interface for basic auth.
+type Verifier struct {
+	hasher Hasher
+	store  CredentialStore
+
+	// dummy is verified for unknown users, so they take as long to reject
+	// as wrong passwords.
+	dummy string
+}
+
+// NewVerifier constructs a Verifier hashing with the hasher.
+func NewVerifier(h Hasher, store CredentialStore) (*Verifier, error) {
+	dummy, err := h.Hash("seed dummy password")
+	if err != nil {
+		return nil, err
+	}
+
+	v := Verifier{
+		hasher: h,
+		store:  store,
+		dummy:  dummy,
+	}
+
+	return &v, nil
+}
+
+// Verify returns the claims of the user if the password matches, and
+// tokens.ErrInvalidCredentials otherwise. The hash is replaced when it does
+// not use the algorithm or parameters of the hasher.
+func (v *Verifier) Verify(ctx context.Context, username, password string) (auth.Claims, error) {
+	c, err := v.store.Credential(ctx, username)
+	switch {
+	case errors.Is(err, ErrNotFound):
+		v.hasher.Verify(v.dummy, password)
+		return auth.Claims{}, tokens.ErrInvalidCredentials
+	case err != nil:
+		return auth.Claims{}, fmt.Errorf("looking up credential: %w", err)
+	}
+
+	rehash, err := v.hasher.Verify(c.Hash, password)
+	switch {
+	case errors.Is(err, ErrMismatch):
+		return auth.Claims{}, tokens.ErrInvalidCredentials
+	case err != nil:
+		return auth.Claims{}, fmt.Errorf("verifying password: %w", err)
+	}
+
+	// The password is only known now, so this is the chance to upgrade the
+	// hash. Failing to do so does not fail the login.
+	if rehash {
+		if hash, err := v.hasher.Hash(password); err == nil {
+			v.store.UpdateHash(ctx, username, hash)
+		}
+	}
+
+	return c.Claims, nil
+}
+
+// Authenticate implements the tokens.Authenticator interface.
+func (v *Verifier) Authenticate(ctx context.Context, c tokens.Credentials) (auth.Claims, error) {
+	return v.Verify(ctx, c.Username, c.Password)
+}
+
+// LoadClaims implements the tokens.ClaimsLoader interface, so refreshed
+// tokens carry the current claims of the user.
+func (v *Verifier) LoadClaims(ctx context.Context, username string) (auth.Claims, error) {
+	c, err := v.store.Credential(ctx, username)
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return auth.Claims{}, tokens.ErrInvalidCredentials
+	case err != nil:
+		return auth.Claims{}, fmt.Errorf("looking up credential: %w", err)
+	}
+	return c.Claims, nil
+}
+
+// =============================================================================
+
+// MemoryStore is an in-memory credential store.
+type MemoryStore struct {
+	mu    sync.RWMutex
+	users map[string]Credential
+}
+
+// NewMemoryStore constructs an empty MemoryStore ready for use.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		users: make(map[string]Credential),
+	}
+}
+
+// Add stores the credential of the user.
+func (ms *MemoryStore) Add(username string, c Credential) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ms.users[username] = c
+}
+
+// Credential implements the CredentialStore interface.
+func (ms *MemoryStore) Credential(ctx context.Context, username string) (Credential, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	c, ok := ms.users[username]
+	if !ok {
+		return Credential{}, ErrNotFound
+	}
+	return c, nil
+}
+
+// UpdateHash implements the CredentialStore interface.
+func (ms *MemoryStore) UpdateHash(ctx context.Context, username, hash string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	c, ok := ms.users[username]
+	if !ok {
+		return ErrNotFound
+	}
+	c.Hash = hash
+	ms.users[username] = c
+	return nil
+}
@@ -0,0 +1,138 @@
+package auth
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Requirement declares the permissions needed to call an endpoint.
+type Requirement struct {
+	// AllOf lists permissions that are all required.
+	AllOf []string
+	// AnyOf lists permissions of which at least one is required.
+	AnyOf []string
+}
+
+// IsZero reports whether no permission is required.
+func (r Requirement) IsZero() bool {
+	return len(r.AllOf) == 0 && len(r.AnyOf) == 0
+}
+
+// Decision is the outcome of a permission check. Reason explains a denial
+// for audit logs.
+type Decision struct {
+	Allowed bool
+	Reason  string
+}
+
+// Permissions maps roles to permissions, such as "greeter:read", and roles
+// to the roles they imply. A permission ending in "*" grants every
+// permission with that prefix, e.g. "greeter:*".
+type Permissions struct {
+	grants  map[string][]string
+	implies map[string][]string
+}
+
+// NewPermissions constructs an empty Permissions ready for use. It must be
+// configured before it is used.
+func NewPermissions() *Permissions {
+	return &Permissions{
+		grants:  make(map[string][]string),
+		implies: make(map[string][]string),
+	}
+}
+
+// Grant gives the permissions to role.
+func (p *Permissions) Grant(role string, perms ...string) *Permissions {
+	p.grants[role] = append(p.grants[role], perms...)
+	return p
+}
+
+// Imply gives role every role in implied, and their permissions.
+// Example: p.Imply(auth.RoleAdmin, auth.RoleUser)
+func (p *Permissions) Imply(role string, implied ...string) *Permissions {
+	p.implies[role] = append(p.implies[role], implied...)
+	return p
+}
+
+// ExpandRoles returns the roles along with every role they imply.
+func (p *Permissions) ExpandRoles(roles []string) []string {
+	if p == nil {
+		return roles
+	}
+
+	seen := make(map[string]bool)
+	var expanded []string
+	var walk func(role string)
+	walk = func(role string) {
+		if seen[role] {
+			return
+		}
+		seen[role] = true
+		expanded = append(expanded, role)
+		for _, r := range p.implies[role] {
+			walk(r)
+		}
+	}
+	for _, r := range roles {
+		walk(r)
+	}
+
+	return expanded
+}
+
+// Granted returns the permissions of the roles, including implied roles.
+func (p *Permissions) Granted(roles []string) []string {
+	if p == nil {
+		return nil
+	}
+
+	var perms []string
+	for _, r := range p.ExpandRoles(roles) {
+		perms = append(perms, p.grants[r]...)
+	}
+	return perms
+}
+
+// Check decides whether the roles meet the requirement.
+func (p *Permissions) Check(roles []string, req Requirement) Decision {
+	if req.IsZero() {
+		return Decision{Allowed: true}
+	}
+
+	granted := p.Granted(roles)
+
+	var missing []string
+	for _, want := range req.AllOf {
+		if !permitted(granted, want) {
+			missing = append(missing, want)
+		}
+	}
+	if len(missing) > 0 {
+		return Decision{Reason: fmt.Sprintf("missing permissions: %s", strings.Join(missing, ", "))}
+	}
+
+	if len(req.AnyOf) > 0 {
+		for _, want := range req.AnyOf {
+			if permitted(granted, want) {
+				return Decision{Allowed: true}
+			}
+		}
+		return Decision{Reason: fmt.Sprintf("missing any of permissions: %s", strings.Join(req.AnyOf, ", "))}
+	}
+
+	return Decision{Allowed: true}
+}
+
+// permitted reports whether a granted permission matches want.
+func permitted(granted []string, want string) bool {
+	for _, g := range granted {
+		if g == want {
+			return true
+		}
+		if strings.HasSuffix(g, "*") && strings.HasPrefix(want, strings.TrimSuffix(g, "*")) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrTokenRevoked is returned by ValidateToken for revoked tokens.
+var ErrTokenRevoked = errors.New("token has been revoked")
+
+// RevocationStore declares a method set of behavior for checking whether a
+// token has been revoked, either by its id (jti) or because every token of
+// its subject issued before some time was revoked.
+type RevocationStore interface {
+	IsRevoked(jti, subject string, issuedAt time.Time) (bool, error)
+}
+
+// SetRevocation configures the store consulted by ValidateToken. It must be
+// called before the Auth is used.
+func (a *Auth) SetRevocation(rs RevocationStore) {
+	a.revocation = rs
+}
+
+// CheckRevoked returns ErrTokenRevoked if the claims have been revoked. It
+// lets services that issue tokens from stored claims, such as on refresh,
+// honour revocations.
+func (a *Auth) CheckRevoked(c Claims) error {
+	if a.revocation == nil {
+		return nil
+	}
+
+	var iat time.Time
+	if c.IssuedAt != nil {
+		iat = c.IssuedAt.Time
+	}
+
+	revoked, err := a.revocation.IsRevoked(c.ID, c.Subject, iat)
+	if err != nil {
+		return fmt.Errorf("checking revocation: %w", err)
+	}
+	if revoked {
+		return ErrTokenRevoked
+	}
+	return nil
+}
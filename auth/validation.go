@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// These are the errors returned by ValidateToken. Use errors.Is to tell
+// them apart.
+var (
+	ErrTokenMalformed        = errors.New("token is malformed")
+	ErrTokenSignatureInvalid = errors.New("token signature is invalid")
+	ErrTokenExpired          = errors.New("token is expired")
+	ErrTokenNotValidYet      = errors.New("token is not valid yet")
+	ErrTokenTooOld           = errors.New("token is too old")
+	ErrTokenInvalidIssuer    = errors.New("token has invalid issuer")
+	ErrTokenInvalidAudience  = errors.New("token has invalid audience")
+	ErrTokenMissingClaim     = errors.New("token is missing a required claim")
+)
+
+// Validation configures the standard claims checked by ValidateToken.
+// The zero value checks the exp, nbf and iat claims, when present, with no
+// leeway.
+type Validation struct {
+	// Issuers lists the accepted iss claims. Empty accepts any issuer.
+	Issuers []string
+	// Audience must be contained in the aud claim, when set.
+	Audience string
+	// Leeway allows for clock skew between services.
+	Leeway time.Duration
+	// MaxAge rejects tokens issued (iat) longer ago, when set.
+	MaxAge time.Duration
+	// RequiredClaims lists registered claims that must be present,
+	// e.g. "exp", "iat", "sub", "jti".
+	RequiredClaims []string
+}
+
+// SetValidation configures the claims checked by ValidateToken. It must be
+// called before the Auth is used.
+func (a *Auth) SetValidation(v Validation) {
+	a.validation = v
+}
+
+// check validates the registered claims at time now.
+func (v Validation) check(c jwt.RegisteredClaims, now time.Time) error {
+	for _, name := range v.RequiredClaims {
+		if !hasClaim(c, name) {
+			return fmt.Errorf("%w: %s", ErrTokenMissingClaim, name)
+		}
+	}
+
+	if c.ExpiresAt != nil && now.After(c.ExpiresAt.Add(v.Leeway)) {
+		return ErrTokenExpired
+	}
+	if c.NotBefore != nil && now.Add(v.Leeway).Before(c.NotBefore.Time) {
+		return ErrTokenNotValidYet
+	}
+	if c.IssuedAt != nil && now.Add(v.Leeway).Before(c.IssuedAt.Time) {
+		return fmt.Errorf("%w: issued in the future", ErrTokenNotValidYet)
+	}
+
+	if v.MaxAge > 0 {
+		if c.IssuedAt == nil {
+			return fmt.Errorf("%w: iat", ErrTokenMissingClaim)
+		}
+		if now.Sub(c.IssuedAt.Time) > v.MaxAge+v.Leeway {
+			return ErrTokenTooOld
+		}
+	}
+
+	if len(v.Issuers) > 0 {
+		ok := false
+		for _, iss := range v.Issuers {
+			ok = ok || iss == c.Issuer
+		}
+		if !ok {
+			return ErrTokenInvalidIssuer
+		}
+	}
+
+	if v.Audience != "" && !c.VerifyAudience(v.Audience, true) {
+		return ErrTokenInvalidAudience
+	}
+
+	return nil
+}
+
+// hasClaim reports whether the registered claim is set.
+func hasClaim(c jwt.RegisteredClaims, name string) bool {
+	switch name {
+	case "iss":
+		return c.Issuer != ""
+	case "sub":
+		return c.Subject != ""
+	case "aud":
+		return len(c.Audience) > 0
+	case "exp":
+		return c.ExpiresAt != nil
+	case "nbf":
+		return c.NotBefore != nil
+	case "iat":
+		return c.IssuedAt != nil
+	case "jti":
+		return c.ID != ""
+	}
+	return false
+}
+
+// parseError maps a jwt parsing error to one of our errors.
+func parseError(err error) error {
+	switch {
+	case errors.Is(err, jwt.ErrTokenMalformed):
+		return fmt.Errorf("%w: %v", ErrTokenMalformed, err)
+	case errors.Is(err, jwt.ErrTokenSignatureInvalid), errors.Is(err, jwt.ErrTokenUnverifiable):
+		return fmt.Errorf("%w: %v", ErrTokenSignatureInvalid, err)
+	}
+	return err
+}
@@ -0,0 +1,237 @@
+// Package concurrency provides concurrency limiting with a bounded wait
+// queue and adaptive (AIMD) limits that shed low priority work first.
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+// ErrLimitExceeded is returned when a request is shed.
+var ErrLimitExceeded = errors.New("concurrency limit exceeded")
+
+// Priority orders requests when shedding load.
+type Priority int
+
+// These are the supported priorities.
+const (
+	// PriorityNormal requests may wait in the queue for a slot.
+	PriorityNormal Priority = iota
+	// PriorityLow requests are never queued and may only use a share
+	// of the limit, so they are shed first.
+	PriorityLow
+)
+
+// Config configures a Limiter.
+type Config struct {
+	// Limit is the initial number of requests in flight.
+	Limit int
+	// QueueSize is the number of requests that may wait for a slot.
+	QueueSize int
+	// QueueTimeout is the longest a request waits for a slot. Default: 1s
+	QueueTimeout time.Duration
+	// LowPriorityShare is the fraction of the limit low priority requests
+	// may use. Default: 0.5
+	LowPriorityShare float64
+
+	// Adaptive enables AIMD adjustment of the limit: it grows by one per
+	// saturated round trip and is multiplied by Backoff whenever a request
+	// takes longer than TargetLatency.
+	Adaptive      bool
+	TargetLatency time.Duration
+	// MinLimit and MaxLimit bound the adaptive limit. Default: 1 and Limit
+	MinLimit int
+	MaxLimit int
+	// Backoff is the multiplicative decrease. Default: 0.9
+	Backoff float64
+}
+
+// Limiter bounds the number of requests in flight.
+type Limiter struct {
+	cfg Config
+
+	mu       sync.Mutex
+	limit    float64
+	inflight int
+	queue    []chan struct{}
+}
+
+// New constructs a Limiter.
+func New(cfg Config) (*Limiter, error) {
+	if cfg.Limit < 1 {
+		return nil, errors.New("limit must be positive")
+	}
+	if cfg.QueueSize < 0 {
+		return nil, errors.New("queue size must not be negative")
+	}
+	if cfg.QueueTimeout == 0 {
+		cfg.QueueTimeout = time.Second
+	}
+	if cfg.LowPriorityShare <= 0 || cfg.LowPriorityShare > 1 {
+		cfg.LowPriorityShare = 0.5
+	}
+	if cfg.Adaptive {
+		if cfg.TargetLatency <= 0 {
+			return nil, errors.New("adaptive limit requires a target latency")
+		}
+		if cfg.MinLimit < 1 {
+			cfg.MinLimit = 1
+		}
+		if cfg.MaxLimit < cfg.Limit {
+			cfg.MaxLimit = cfg.Limit
+		}
+		if cfg.Backoff <= 0 || cfg.Backoff >= 1 {
+			cfg.Backoff = 0.9
+		}
+	}
+
+	l := Limiter{
+		cfg:   cfg,
+		limit: float64(cfg.Limit),
+	}
+
+	return &l, nil
+}
+
+// Limit returns the current limit.
+func (l *Limiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return int(l.limit)
+}
+
+// InFlight returns the number of requests holding a slot.
+func (l *Limiter) InFlight() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.inflight
+}
+
+// Acquire waits for a slot. The returned release func must be called once
+// the request completes.
+func (l *Limiter) Acquire(ctx context.Context, p Priority) (func(), error) {
+	l.mu.Lock()
+
+	if l.inflight < l.admit(p) && len(l.queue) == 0 {
+		l.inflight++
+		l.mu.Unlock()
+		return l.releaser(), nil
+	}
+
+	if p == PriorityLow || len(l.queue) >= l.cfg.QueueSize {
+		l.mu.Unlock()
+		return nil, ErrLimitExceeded
+	}
+
+	ready := make(chan struct{})
+	l.queue = append(l.queue, ready)
+	l.mu.Unlock()
+
+	timer := time.NewTimer(l.cfg.QueueTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-ready:
+		return l.releaser(), nil
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for i, q := range l.queue {
+		if q == ready {
+			l.queue = append(l.queue[:i], l.queue[i+1:]...)
+			return nil, ErrLimitExceeded
+		}
+	}
+
+	// The slot was handed over while timing out.
+	return l.releaser(), nil
+}
+
+// admit returns the number of requests in flight allowed for p.
+func (l *Limiter) admit(p Priority) int {
+	if p == PriorityLow {
+		return int(math.Max(1, math.Floor(l.limit*l.cfg.LowPriorityShare)))
+	}
+	return int(l.limit)
+}
+
+// releaser returns a func that frees a slot exactly once.
+func (l *Limiter) releaser() func() {
+	start := time.Now()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.release(time.Since(start))
+		})
+	}
+}
+
+// release frees a slot, adapts the limit and hands free slots to waiters.
+func (l *Limiter) release(latency time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.cfg.Adaptive {
+		switch {
+		case latency > l.cfg.TargetLatency:
+			l.limit = math.Max(float64(l.cfg.MinLimit), l.limit*l.cfg.Backoff)
+		case l.inflight >= int(l.limit):
+			l.limit = math.Min(float64(l.cfg.MaxLimit), l.limit+1/l.limit)
+		}
+	}
+
+	l.inflight--
+	for len(l.queue) > 0 && l.inflight < int(l.limit) {
+		close(l.queue[0])
+		l.queue = l.queue[1:]
+		l.inflight++
+	}
+}
+
+// =============================================================================
+
+// Shedder applies a server wide limiter and per route limiters.
+type Shedder struct {
+	// Server limits every request. Optional.
+	Server *Limiter
+	// Routes limits requests by path.
+	Routes map[string]*Limiter
+	// Priorities sets the priority of requests by path.
+	// Default: PriorityNormal
+	Priorities map[string]Priority
+}
+
+// Acquire takes a slot from the route limiter then the server limiter.
+func (s *Shedder) Acquire(ctx context.Context, route string) (func(), error) {
+	p := s.Priorities[route]
+
+	var releases []func()
+	release := func() {
+		for i := len(releases) - 1; i >= 0; i-- {
+			releases[i]()
+		}
+	}
+
+	for _, l := range []*Limiter{s.Routes[route], s.Server} {
+		if l == nil {
+			continue
+		}
+		r, err := l.Acquire(ctx, p)
+		if err != nil {
+			release()
+			return nil, err
+		}
+		releases = append(releases, r)
+	}
+
+	return release, nil
+}
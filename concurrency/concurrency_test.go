@@ -0,0 +1,80 @@
+package concurrency_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gitamped/seed/concurrency"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+func Test_Limiter(t *testing.T) {
+	t.Log("Given the need to bound requests in flight.")
+	{
+		ctx := context.Background()
+
+		testID := 0
+		t.Logf("\tTest %d:\tWhen the limit is reached.", testID)
+		{
+			l, err := concurrency.New(concurrency.Config{Limit: 2, QueueSize: 1, QueueTimeout: time.Second})
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create a limiter: %v", failed, testID, err)
+			}
+
+			release, err := l.Acquire(ctx, concurrency.PriorityNormal)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould acquire a slot: %v", failed, testID, err)
+			}
+			if _, err := l.Acquire(ctx, concurrency.PriorityLow); err != concurrency.ErrLimitExceeded {
+				t.Fatalf("\t%s\tTest %d:\tShould shed low priority requests first: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould shed low priority requests first.", success, testID)
+
+			if _, err := l.Acquire(ctx, concurrency.PriorityNormal); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould acquire the last slot: %v", failed, testID, err)
+			}
+
+			acquired := make(chan error)
+			go func() {
+				_, err := l.Acquire(ctx, concurrency.PriorityNormal)
+				acquired <- err
+			}()
+			time.Sleep(10 * time.Millisecond)
+
+			if _, err := l.Acquire(ctx, concurrency.PriorityNormal); err != concurrency.ErrLimitExceeded {
+				t.Fatalf("\t%s\tTest %d:\tShould reject requests when the queue is full: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould reject requests when the queue is full.", success, testID)
+
+			release()
+			if err := <-acquired; err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould hand the slot to the queued request: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould hand the slot to the queued request.", success, testID)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tWhen requests are slower than the target latency.", testID)
+		{
+			l, err := concurrency.New(concurrency.Config{Limit: 10, Adaptive: true, TargetLatency: time.Nanosecond, Backoff: 0.5})
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create a limiter: %v", failed, testID, err)
+			}
+
+			release, _ := l.Acquire(ctx, concurrency.PriorityNormal)
+			time.Sleep(time.Millisecond)
+			release()
+
+			if got := l.Limit(); got != 5 {
+				t.Fatalf("\t%s\tTest %d:\tShould decrease the limit: %d", failed, testID, got)
+			}
+			t.Logf("\t%s\tTest %d:\tShould decrease the limit.", success, testID)
+		}
+	}
+}
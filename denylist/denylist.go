@@ -0,0 +1,87 @@
+// Package denylist implements the auth.RevocationStore interface with an
+// in-memory store and a store persisted to a file.
+package denylist
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gitamped/seed/auth"
+)
+
+// Store declares a method set of behavior for revoking tokens, satisfied
+// by both MemoryStore and FileStore.
+type Store interface {
+	auth.RevocationStore
+	RevokeID(jti string, expiresAt time.Time) error
+	RevokeSubject(subject string, before time.Time) error
+}
+
+// MemoryStore is an in-memory revocation store.
+type MemoryStore struct {
+	mu       sync.RWMutex
+	ids      map[string]time.Time
+	subjects map[string]time.Time
+}
+
+// New constructs an empty MemoryStore ready for use.
+func New() *MemoryStore {
+	return &MemoryStore{
+		ids:      make(map[string]time.Time),
+		subjects: make(map[string]time.Time),
+	}
+}
+
+// RevokeID revokes the token with id jti. The entry is kept until the
+// token expires at expiresAt.
+func (ms *MemoryStore) RevokeID(jti string, expiresAt time.Time) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ms.revokeID(jti, expiresAt)
+	return nil
+}
+
+// RevokeSubject revokes every token of subject issued before the time,
+// e.g. to log a user out of every session.
+func (ms *MemoryStore) RevokeSubject(subject string, before time.Time) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ms.revokeSubject(subject, before)
+	return nil
+}
+
+// IsRevoked implements the auth.RevocationStore interface.
+func (ms *MemoryStore) IsRevoked(jti, subject string, issuedAt time.Time) (bool, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	if _, found := ms.ids[jti]; found && jti != "" {
+		return true, nil
+	}
+	if before, found := ms.subjects[subject]; found && subject != "" {
+		// Tokens without an issued at time can not be told apart.
+		return issuedAt.IsZero() || issuedAt.Before(before), nil
+	}
+	return false, nil
+}
+
+// revokeID adds the id and prunes expired ids. The caller must hold mu.
+func (ms *MemoryStore) revokeID(jti string, expiresAt time.Time) {
+	now := time.Now()
+	for id, exp := range ms.ids {
+		if now.After(exp) {
+			delete(ms.ids, id)
+		}
+	}
+	ms.ids[jti] = expiresAt
+}
+
+// revokeSubject moves the revocation time of the subject forward. The
+// caller must hold mu.
+func (ms *MemoryStore) revokeSubject(subject string, before time.Time) {
+	if before.After(ms.subjects[subject]) {
+		ms.subjects[subject] = before
+	}
+}
@@ -0,0 +1,137 @@
+package denylist_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gitamped/seed/auth"
+	"github.com/gitamped/seed/denylist"
+	"github.com/gitamped/seed/keystore"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+func Test_Revocation(t *testing.T) {
+	t.Log("Given the need to revoke tokens before they expire.")
+	{
+		privateKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+		ks := keystore.New()
+		ks.Add(privateKey, "kid")
+		a, err := auth.New("kid", ks)
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to create an authenticator: %v", failed, err)
+		}
+
+		path := filepath.Join(t.TempDir(), "denylist.json")
+		store, err := denylist.Open(path)
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to open the denylist: %v", failed, err)
+		}
+		a.SetRevocation(store)
+
+		now := time.Now().UTC()
+		claims := auth.Claims{
+			RegisteredClaims: jwt.RegisteredClaims{
+				Subject:   "5cf37266-3473-4006-984f-9325122678b7",
+				ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+				IssuedAt:  jwt.NewNumericDate(now.Add(-time.Minute)),
+			},
+		}
+
+		testID := 0
+		t.Logf("\tTest %d:\tWhen a token is revoked by id.", testID)
+		{
+			token, _ := a.GenerateToken(claims)
+			parsed, err := a.ValidateToken(token)
+			if err != nil || parsed.ID == "" {
+				t.Fatalf("\t%s\tTest %d:\tShould assign a token id : %q : %v", failed, testID, parsed.ID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould assign a token id.", success, testID)
+
+			if err := store.RevokeID(parsed.ID, parsed.ExpiresAt.Time); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to revoke the token: %v", failed, testID, err)
+			}
+			if _, err := a.ValidateToken(token); !errors.Is(err, auth.ErrTokenRevoked) {
+				t.Fatalf("\t%s\tTest %d:\tShould reject the revoked token : %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould reject the revoked token.", success, testID)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tWhen every token of a subject is revoked.", testID)
+		{
+			token, _ := a.GenerateToken(claims)
+			if err := store.RevokeSubject(claims.Subject, now.Add(-30*time.Second)); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to revoke the subject: %v", failed, testID, err)
+			}
+			if _, err := a.ValidateToken(token); !errors.Is(err, auth.ErrTokenRevoked) {
+				t.Fatalf("\t%s\tTest %d:\tShould reject tokens issued before : %v", failed, testID, err)
+			}
+
+			claims.IssuedAt = jwt.NewNumericDate(now)
+			token, _ = a.GenerateToken(claims)
+			if _, err := a.ValidateToken(token); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould accept tokens issued after : %v", failed, testID, err)
+			}
+
+			claims.IssuedAt = nil
+			token, _ = a.GenerateToken(claims)
+			if _, err := a.ValidateToken(token); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould accept tokens issued after without an explicit iat : %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould only reject tokens issued before.", success, testID)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tWhen the denylist is reopened.", testID)
+		{
+			reopened, err := denylist.Open(path)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to reopen the denylist: %v", failed, testID, err)
+			}
+			if revoked, _ := reopened.IsRevoked("", claims.Subject, now.Add(-time.Minute)); !revoked {
+				t.Fatalf("\t%s\tTest %d:\tShould keep revocations.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould keep revocations.", success, testID)
+		}
+	}
+}
+
+func Test_Stores(t *testing.T) {
+	t.Log("Given the need to swap revocation stores.")
+	{
+		file, err := denylist.Open(filepath.Join(t.TempDir(), "denylist.json"))
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to open the denylist: %v", failed, err)
+		}
+
+		now := time.Now()
+		for testID, store := range []denylist.Store{denylist.New(), file} {
+			t.Logf("\tTest %d:\tWhen revoking with a %T.", testID, store)
+			{
+				if err := store.RevokeID("jti", now.Add(time.Hour)); err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to revoke the token: %v", failed, testID, err)
+				}
+				if err := store.RevokeSubject("sub", now); err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to revoke the subject: %v", failed, testID, err)
+				}
+				if revoked, _ := store.IsRevoked("jti", "", now); !revoked {
+					t.Fatalf("\t%s\tTest %d:\tShould revoke the token.", failed, testID)
+				}
+				if revoked, _ := store.IsRevoked("", "sub", now.Add(-time.Second)); !revoked {
+					t.Fatalf("\t%s\tTest %d:\tShould revoke the subject.", failed, testID)
+				}
+				t.Logf("\t%s\tTest %d:\tShould revoke tokens and subjects.", success, testID)
+			}
+		}
+	}
+}
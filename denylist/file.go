@@ -0,0 +1,82 @@
+package denylist
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+
+	"github.com/gitamped/seed/internal/jsonfile"
+)
+
+// FileStore is a MemoryStore persisted as JSON to a file, so revocations
+// survive restarts.
+type FileStore struct {
+	*MemoryStore
+	path string
+}
+
+// document is the file representation of the store.
+type document struct {
+	IDs      map[string]time.Time `json:"ids"`
+	Subjects map[string]time.Time `json:"subjects"`
+}
+
+// Open constructs a FileStore, loading the revocations in the file at path
+// if it exists.
+func Open(path string) (*FileStore, error) {
+	f := FileStore{
+		MemoryStore: New(),
+		path:        path,
+	}
+
+	b, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return &f, nil
+	case err != nil:
+		return nil, fmt.Errorf("reading denylist: %w", err)
+	}
+
+	var doc document
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("decoding denylist: %w", err)
+	}
+	for id, exp := range doc.IDs {
+		f.ids[id] = exp
+	}
+	for sub, before := range doc.Subjects {
+		f.subjects[sub] = before
+	}
+
+	return &f, nil
+}
+
+// RevokeID revokes the token with id jti and saves the store.
+func (f *FileStore) RevokeID(jti string, expiresAt time.Time) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.revokeID(jti, expiresAt)
+	return f.save()
+}
+
+// RevokeSubject revokes every token of subject issued before the time and
+// saves the store.
+func (f *FileStore) RevokeSubject(subject string, before time.Time) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.revokeSubject(subject, before)
+	return f.save()
+}
+
+// save atomically writes the store to its file. The caller must hold mu.
+func (f *FileStore) save() error {
+	if err := jsonfile.Write(f.path, document{IDs: f.ids, Subjects: f.subjects}); err != nil {
+		return fmt.Errorf("saving denylist: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package jsonfile_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gitamped/seed/internal/jsonfile"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+func Test_Write(t *testing.T) {
+	t.Log("Given the need to persist documents atomically.")
+	{
+		dir := t.TempDir()
+		path := filepath.Join(dir, "doc.json")
+
+		for testID, doc := range []map[string]int{{"a": 1}, {"b": 2}} {
+			t.Logf("\tTest %d:\tWhen writing document %v.", testID, doc)
+			{
+				if err := jsonfile.Write(path, doc); err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to write the document : %v", failed, testID, err)
+				}
+
+				b, err := os.ReadFile(path)
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to read the document : %v", failed, testID, err)
+				}
+				var got map[string]int
+				if err := json.Unmarshal(b, &got); err != nil || len(got) != 1 || got["a"] != doc["a"] || got["b"] != doc["b"] {
+					t.Fatalf("\t%s\tTest %d:\tShould replace the document : %s", failed, testID, b)
+				}
+				t.Logf("\t%s\tTest %d:\tShould replace the document.", success, testID)
+
+				entries, _ := os.ReadDir(dir)
+				if len(entries) != 1 {
+					t.Fatalf("\t%s\tTest %d:\tShould not leave temporary files : %d files", failed, testID, len(entries))
+				}
+				t.Logf("\t%s\tTest %d:\tShould not leave temporary files.", success, testID)
+			}
+		}
+
+		testID := 2
+		t.Logf("\tTest %d:\tWhen the document can not be encoded.", testID)
+		{
+			if err := jsonfile.Write(path, func() {}); err == nil {
+				t.Fatalf("\t%s\tTest %d:\tShould fail to write the document.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould fail to write the document.", success, testID)
+		}
+	}
+}
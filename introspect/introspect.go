@@ -0,0 +1,249 @@
+// Package introspect authenticates opaque access tokens with an OAuth2
+// token introspection endpoint as described in RFC 7662.
+package introspect
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gitamped/seed/auth"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// ErrInactive is returned when the endpoint reports the token is not active.
+var ErrInactive = errors.New("token is not active")
+
+// Config configures a Client.
+type Config struct {
+	// Client calls the endpoint. Default: a client with a 10s timeout
+	Client *http.Client
+	// ClientID and ClientSecret authenticate the resource server to the
+	// endpoint with basic auth. Optional.
+	ClientID     string
+	ClientSecret string
+	// ActiveTTL is how long an active result is cached. It is never cached
+	// beyond the expiry of the token. Default: 1m
+	ActiveTTL time.Duration
+	// InactiveTTL is how long an inactive result is cached. Default: 10s
+	InactiveTTL time.Duration
+	// MaxEntries bounds the number of cached results. Default: 10000
+	MaxEntries int
+	// ScopeRoles grants roles to tokens with a scope.
+	ScopeRoles map[string][]string
+	// TrustRoles grants the roles listed in the roles member of the
+	// response. Only enable it for endpoints whose roles are meant for this
+	// service.
+	TrustRoles bool
+	// Issuers lists the accepted iss members. Empty accepts any issuer.
+	Issuers []string
+	// Audience must be contained in the aud member, when set.
+	Audience string
+}
+
+// Response is the introspection response of an endpoint.
+type Response struct {
+	Active    bool             `json:"active"`
+	Scope     string           `json:"scope,omitempty"`
+	ClientID  string           `json:"client_id,omitempty"`
+	Username  string           `json:"username,omitempty"`
+	TokenType string           `json:"token_type,omitempty"`
+	Exp       int64            `json:"exp,omitempty"`
+	Iat       int64            `json:"iat,omitempty"`
+	Nbf       int64            `json:"nbf,omitempty"`
+	Sub       string           `json:"sub,omitempty"`
+	Aud       jwt.ClaimStrings `json:"aud,omitempty"`
+	Iss       string           `json:"iss,omitempty"`
+	Jti       string           `json:"jti,omitempty"`
+	// Roles is an extension member some providers use for roles.
+	Roles []string `json:"roles,omitempty"`
+}
+
+// entry is a cached introspection result.
+type entry struct {
+	claims  auth.Claims
+	err     error
+	expires time.Time
+}
+
+// Client introspects tokens, caching the results.
+type Client struct {
+	url string
+	cfg Config
+
+	mu    sync.Mutex
+	cache map[[sha256.Size]byte]entry
+}
+
+// New constructs a Client for the introspection endpoint url.
+func New(url string, cfg Config) *Client {
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if cfg.ActiveTTL <= 0 {
+		cfg.ActiveTTL = time.Minute
+	}
+	if cfg.InactiveTTL <= 0 {
+		cfg.InactiveTTL = 10 * time.Second
+	}
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = 10000
+	}
+
+	return &Client{
+		url:   url,
+		cfg:   cfg,
+		cache: make(map[[sha256.Size]byte]entry),
+	}
+}
+
+// Authenticate returns the claims of an active token, or ErrInactive.
+// Active tokens for another issuer or audience are rejected with
+// auth.ErrTokenInvalidIssuer or auth.ErrTokenInvalidAudience. Results
+// are cached by a hash of the token so tokens are not kept in memory.
+func (c *Client) Authenticate(ctx context.Context, token string) (auth.Claims, error) {
+	key := sha256.Sum256([]byte(token))
+	now := time.Now()
+
+	c.mu.Lock()
+	e, ok := c.cache[key]
+	c.mu.Unlock()
+	if ok && now.Before(e.expires) {
+		return e.claims, e.err
+	}
+
+	resp, err := c.Introspect(ctx, token)
+	if err != nil {
+		return auth.Claims{}, err
+	}
+
+	e = entry{err: ErrInactive, expires: now.Add(c.cfg.InactiveTTL)}
+	if resp.Active {
+		e.err = c.check(resp)
+		if e.err == nil {
+			e.claims = c.claims(resp)
+		}
+		e.expires = now.Add(c.cfg.ActiveTTL)
+		if resp.Exp != 0 && time.Unix(resp.Exp, 0).Before(e.expires) {
+			e.expires = time.Unix(resp.Exp, 0)
+		}
+	}
+	c.store(key, e, now)
+
+	return e.claims, e.err
+}
+
+// check validates the issuer and audience of an active response.
+func (c *Client) check(r Response) error {
+	if len(c.cfg.Issuers) > 0 {
+		ok := false
+		for _, iss := range c.cfg.Issuers {
+			ok = ok || iss == r.Iss
+		}
+		if !ok {
+			return auth.ErrTokenInvalidIssuer
+		}
+	}
+
+	if c.cfg.Audience != "" {
+		rc := jwt.RegisteredClaims{Audience: r.Aud}
+		if !rc.VerifyAudience(c.cfg.Audience, true) {
+			return auth.ErrTokenInvalidAudience
+		}
+	}
+
+	return nil
+}
+
+// Introspect calls the endpoint for the token without using the cache.
+func (c *Client) Introspect(ctx context.Context, token string) (Response, error) {
+	form := url.Values{"token": {token}, "token_type_hint": {"access_token"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, strings.NewReader(form.Encode()))
+	if err != nil {
+		return Response{}, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if c.cfg.ClientID != "" {
+		req.SetBasicAuth(url.QueryEscape(c.cfg.ClientID), url.QueryEscape(c.cfg.ClientSecret))
+	}
+
+	resp, err := c.cfg.Client.Do(req)
+	if err != nil {
+		return Response{}, fmt.Errorf("introspecting token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Response{}, fmt.Errorf("introspecting token: unexpected status %d", resp.StatusCode)
+	}
+
+	var r Response
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&r); err != nil {
+		return Response{}, fmt.Errorf("decoding introspection response: %w", err)
+	}
+
+	return r, nil
+}
+
+// claims converts an active response into claims.
+func (c *Client) claims(r Response) auth.Claims {
+	subject := r.Sub
+	if subject == "" {
+		subject = r.Username
+	}
+
+	claims := auth.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:   r.Iss,
+			Subject:  subject,
+			Audience: r.Aud,
+			ID:       r.Jti,
+		},
+	}
+	if c.cfg.TrustRoles {
+		claims.Roles = append(claims.Roles, r.Roles...)
+	}
+	if r.Exp != 0 {
+		claims.ExpiresAt = jwt.NewNumericDate(time.Unix(r.Exp, 0))
+	}
+	if r.Iat != 0 {
+		claims.IssuedAt = jwt.NewNumericDate(time.Unix(r.Iat, 0))
+	}
+	if r.Nbf != 0 {
+		claims.NotBefore = jwt.NewNumericDate(time.Unix(r.Nbf, 0))
+	}
+
+	for _, scope := range strings.Fields(r.Scope) {
+		claims.Roles = append(claims.Roles, c.cfg.ScopeRoles[scope]...)
+	}
+
+	return claims
+}
+
+// store caches the entry, dropping expired entries when the cache is full.
+func (c *Client) store(key [sha256.Size]byte, e entry, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.cache) >= c.cfg.MaxEntries {
+		for k, old := range c.cache {
+			if !now.Before(old.expires) {
+				delete(c.cache, k)
+			}
+		}
+	}
+	if len(c.cache) >= c.cfg.MaxEntries {
+		return
+	}
+
+	c.cache[key] = e
+}
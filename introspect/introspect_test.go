@@ -0,0 +1,163 @@
+package introspect_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gitamped/seed/auth"
+	"github.com/gitamped/seed/introspect"
+	"github.com/gitamped/seed/mid"
+	"github.com/gitamped/seed/server"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+func Test_Introspection(t *testing.T) {
+	t.Log("Given the need to authenticate opaque tokens.")
+	{
+		var calls int32
+		idp := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			if id, secret, _ := r.BasicAuth(); id != "seed" || secret != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			resp := introspect.Response{Active: false}
+			switch r.PostFormValue("token") {
+			case "admin-token":
+				resp = introspect.Response{
+					Active: true,
+					Sub:    "admin",
+					Scope:  "openid admin",
+					Exp:    time.Now().Add(time.Hour).Unix(),
+					Iss:    "https://idp.example.com",
+					Aud:    []string{"seed"},
+				}
+			case "user-token":
+				resp = introspect.Response{
+					Active:   true,
+					Username: "user",
+					Roles:    []string{auth.RoleAdmin},
+					Iss:      "https://idp.example.com",
+					Aud:      []string{"seed"},
+				}
+			case "other-audience-token":
+				resp = introspect.Response{
+					Active: true,
+					Sub:    "admin",
+					Scope:  "admin",
+					Iss:    "https://idp.example.com",
+					Aud:    []string{"other"},
+				}
+			case "other-issuer-token":
+				resp = introspect.Response{
+					Active: true,
+					Sub:    "admin",
+					Scope:  "admin",
+					Iss:    "https://evil.example.com",
+					Aud:    []string{"seed"},
+				}
+			}
+			json.NewEncoder(w).Encode(resp)
+		}))
+		defer idp.Close()
+
+		c := introspect.New(idp.URL, introspect.Config{
+			ClientID:     "seed",
+			ClientSecret: "secret",
+			ScopeRoles:   map[string][]string{"admin": {auth.RoleAdmin}},
+			Issuers:      []string{"https://idp.example.com"},
+			Audience:     "seed",
+		})
+
+		mw := append([]mid.Middleware{mid.ChainAuthMiddleware(mid.IntrospectionAuthenticator(c))}, mid.CommonMiddleware...)
+		s := server.NewServer(mw)
+		s.Register("AdminService", "Get", server.RPCEndpoint{
+			Roles: []string{auth.RoleAdmin},
+			Handler: func(g server.GenericRequest, b []byte) (any, error) {
+				return struct{ Subject string }{g.Subject()}, nil
+			},
+		})
+
+		ttable := []struct {
+			TestTitle          string
+			Token              string
+			ExpectedStatusCode int
+		}{
+			{"When the token is active with an admin scope", "admin-token", http.StatusOK},
+			{"When the token is active with roles that are not trusted", "user-token", http.StatusForbidden},
+			{"When the token is not active", "revoked-token", http.StatusUnauthorized},
+			{"When the token is for another audience", "other-audience-token", http.StatusUnauthorized},
+			{"When the token is from another issuer", "other-issuer-token", http.StatusUnauthorized},
+		}
+
+		for testID, td := range ttable {
+			t.Logf("\tTest %d:\t%s.", testID, td.TestTitle)
+			{
+				r := httptest.NewRequest(http.MethodPost, "/v1/AdminService.Get", bytes.NewBufferString(`{}`))
+				r.Header.Set("Authorization", "Bearer "+td.Token)
+				w := httptest.NewRecorder()
+				s.ServeHTTP(w, r)
+
+				if w.Code != td.ExpectedStatusCode {
+					t.Fatalf("\t%s\tTest %d:\tShould receive a status code of %d for the response : %v", failed, testID, td.ExpectedStatusCode, w.Code)
+				}
+				t.Logf("\t%s\tTest %d:\tShould receive a status code of %d for the response.", success, testID, td.ExpectedStatusCode)
+			}
+		}
+
+		testID := len(ttable)
+		t.Logf("\tTest %d:\tWhen tokens are introspected again.", testID)
+		{
+			before := atomic.LoadInt32(&calls)
+			claims, err := c.Authenticate(context.Background(), "user-token")
+			if err != nil || claims.Subject != "user" {
+				t.Fatalf("\t%s\tTest %d:\tShould map the username to the subject : %v %+v", failed, testID, err, claims)
+			}
+			if _, err := c.Authenticate(context.Background(), "revoked-token"); !errors.Is(err, introspect.ErrInactive) {
+				t.Fatalf("\t%s\tTest %d:\tShould report the token is not active : %v", failed, testID, err)
+			}
+			if _, err := c.Authenticate(context.Background(), "other-audience-token"); !errors.Is(err, auth.ErrTokenInvalidAudience) {
+				t.Fatalf("\t%s\tTest %d:\tShould report the audience is invalid : %v", failed, testID, err)
+			}
+			if after := atomic.LoadInt32(&calls); after != before {
+				t.Fatalf("\t%s\tTest %d:\tShould use cached results : %d calls", failed, testID, after-before)
+			}
+			t.Logf("\t%s\tTest %d:\tShould use cached results.", success, testID)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tWhen the endpoint rejects the client.", testID)
+		{
+			bad := introspect.New(idp.URL, introspect.Config{ClientID: "seed", ClientSecret: "wrong"})
+			_, err := bad.Authenticate(context.Background(), "admin-token")
+			if err == nil || errors.Is(err, introspect.ErrInactive) {
+				t.Fatalf("\t%s\tTest %d:\tShould fail without caching a result : %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould fail without caching a result.", success, testID)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tWhen the roles of the endpoint are trusted.", testID)
+		{
+			trusted := introspect.New(idp.URL, introspect.Config{ClientID: "seed", ClientSecret: "secret", TrustRoles: true})
+			claims, err := trusted.Authenticate(context.Background(), "user-token")
+			if err != nil || !claims.Authorized(auth.RoleAdmin) {
+				t.Fatalf("\t%s\tTest %d:\tShould grant the roles of the response : %v %+v", failed, testID, err, claims)
+			}
+			t.Logf("\t%s\tTest %d:\tShould grant the roles of the response.", success, testID)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package jwks
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// PublicKeySource declares a method set of behavior for listing the public
+// keys to publish.
+type PublicKeySource interface {
+	KIDs() []string
+	VerifyingKey(kid string) (crypto.PublicKey, string, error)
+}
+
+// NewSet builds the key set for every key in the source.
+func NewSet(src PublicKeySource) (Set, error) {
+	kids := src.KIDs()
+	sort.Strings(kids)
+
+	s := Set{Keys: make([]Key, 0, len(kids))}
+	for _, kid := range kids {
+		pub, alg, err := src.VerifyingKey(kid)
+		if err != nil {
+			// The key was removed while listing.
+			continue
+		}
+		k, err := NewKey(kid, alg, pub)
+		if err != nil {
+			return Set{}, fmt.Errorf("encoding kid %s: %w", kid, err)
+		}
+		s.Keys = append(s.Keys, k)
+	}
+
+	return s, nil
+}
+
+// Handler serves the public keys of the source as a JWKS document, which
+// may be cached by clients for maxAge.
+// Example: http.Handle("/.well-known/jwks.json", jwks.Handler(ks, time.Hour))
+func Handler(src PublicKeySource, maxAge time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "405 Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		s, err := NewSet(src)
+		if err != nil {
+			log.Printf("jwks: %s\n", err)
+			http.Error(w, "500 Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		b, err := json.Marshal(s)
+		if err != nil {
+			log.Printf("jwks: encode json: %s\n", err)
+			http.Error(w, "500 Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		sum := sha256.Sum256(b)
+		etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds())))
+		w.Header().Set("ETag", etag)
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/jwk-set+json")
+		w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodGet {
+			w.Write(b)
+		}
+	})
+}
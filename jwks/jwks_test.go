@@ -0,0 +1,269 @@
+package jwks_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gitamped/seed/auth"
+	"github.com/gitamped/seed/jwks"
+	"github.com/gitamped/seed/keystore"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+func Test_Handler(t *testing.T) {
+	t.Log("Given the need to publish the public keys of the keystore.")
+	{
+		rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+		ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+
+		ks := keystore.New()
+		ks.Add(rsaKey, "rsa")
+		ks.AddSigner(ecKey, "ec")
+		ks.AddSigner(edKey, "ed")
+
+		h := jwks.Handler(ks, time.Hour)
+
+		testID := 0
+		t.Logf("\tTest %d:\tWhen fetching the key set.", testID)
+		{
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("\t%s\tTest %d:\tShould receive a status code of 200 : %v", failed, testID, w.Code)
+			}
+			if got := w.Header().Get("Cache-Control"); got != "public, max-age=3600" {
+				t.Fatalf("\t%s\tTest %d:\tShould set cache headers : %s", failed, testID, got)
+			}
+			t.Logf("\t%s\tTest %d:\tShould serve the key set with cache headers.", success, testID)
+
+			var s jwks.Set
+			if err := json.NewDecoder(w.Body).Decode(&s); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to unmarshal the key set : %v", failed, testID, err)
+			}
+
+			for _, kid := range []string{"rsa", "ec", "ed"} {
+				k, ok := s.Lookup(kid)
+				if !ok || k.Use != "sig" || k.Alg == "" {
+					t.Fatalf("\t%s\tTest %d:\tShould publish kid %s : %+v", failed, testID, kid, k)
+				}
+				pub, err := k.PublicKey()
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to decode kid %s : %v", failed, testID, kid, err)
+				}
+				want, _, _ := ks.VerifyingKey(kid)
+				if eq, ok := want.(interface{ Equal(crypto.PublicKey) bool }); !ok || !eq.Equal(pub) {
+					t.Fatalf("\t%s\tTest %d:\tShould decode the same key for kid %s.", failed, testID, kid)
+				}
+			}
+			t.Logf("\t%s\tTest %d:\tShould publish every key.", success, testID)
+
+			r := httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+			r.Header.Set("If-None-Match", w.Header().Get("ETag"))
+			w = httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			if w.Code != http.StatusNotModified {
+				t.Fatalf("\t%s\tTest %d:\tShould receive a status code of 304 : %v", failed, testID, w.Code)
+			}
+			t.Logf("\t%s\tTest %d:\tShould honor If-None-Match.", success, testID)
+		}
+	}
+}
+
+func Test_Remote(t *testing.T) {
+	t.Log("Given the need to verify tokens without holding private keys.")
+	{
+		ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		ks := keystore.New()
+		ks.AddSigner(ecKey, "ec")
+
+		var fetches, slow int32
+		gate := make(chan struct{})
+		h := jwks.Handler(ks, time.Hour)
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&fetches, 1)
+			if atomic.LoadInt32(&slow) == 1 {
+				<-gate
+			}
+			h.ServeHTTP(w, r)
+		}))
+		defer srv.Close()
+
+		signer, err := auth.NewWithSigners("ec", ks)
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to create an authenticator: %v", failed, err)
+		}
+		remote := jwks.NewRemote(srv.URL, jwks.RemoteConfig{MinRefresh: time.Hour})
+		verifier, err := auth.NewVerifier(remote, "ES256")
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to create a verifier: %v", failed, err)
+		}
+
+		claims := auth.Claims{
+			RegisteredClaims: jwt.RegisteredClaims{
+				Subject:   "5cf37266-3473-4006-984f-9325122678b7",
+				ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(time.Hour)),
+			},
+			Roles: []string{auth.RoleUser},
+		}
+
+		testID := 0
+		t.Logf("\tTest %d:\tWhen validating a token signed by the key set.", testID)
+		{
+			token, _ := signer.GenerateToken(claims)
+			if _, err := verifier.ValidateToken(token); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to parse the claims: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to parse the claims.", success, testID)
+
+			if _, err := verifier.GenerateToken(claims); err != auth.ErrVerifyOnly {
+				t.Fatalf("\t%s\tTest %d:\tShould not be able to sign tokens: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould not be able to sign tokens.", success, testID)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tWhen flooded with unknown kids.", testID)
+		{
+			ecKey2, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			ks.AddSigner(ecKey2, "unknown")
+			other, _ := auth.NewWithSigners("unknown", ks)
+			token, _ := other.GenerateToken(claims)
+
+			for i := 0; i < 10; i++ {
+				verifier.ValidateToken(token)
+			}
+			if got := atomic.LoadInt32(&fetches); got != 1 {
+				t.Fatalf("\t%s\tTest %d:\tShould rate limit refreshes : %d fetches", failed, testID, got)
+			}
+			t.Logf("\t%s\tTest %d:\tShould rate limit refreshes.", success, testID)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tWhen a refresh is slow.", testID)
+		{
+			atomic.StoreInt32(&slow, 1)
+			refreshed := make(chan error)
+			go func() { refreshed <- remote.Refresh() }()
+			for atomic.LoadInt32(&fetches) != 2 {
+				time.Sleep(time.Millisecond)
+			}
+
+			looked := make(chan error)
+			go func() {
+				_, _, err := remote.VerifyingKey("ec")
+				looked <- err
+			}()
+
+			select {
+			case err := <-looked:
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould find known keys during a refresh : %v", failed, testID, err)
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("\t%s\tTest %d:\tShould not wait for the refresh to find known keys.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould not wait for the refresh to find known keys.", success, testID)
+
+			close(gate)
+			if err := <-refreshed; err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould complete the refresh : %v", failed, testID, err)
+			}
+		}
+	}
+}
+
+func Test_RemoteStale(t *testing.T) {
+	t.Log("Given the need to keep verifying tokens while the key set url is slow or down.")
+	{
+		ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		ks := keystore.New()
+		ks.AddSigner(ecKey, "ec")
+
+		var fetches, down int32
+		gate := make(chan struct{})
+		h := jwks.Handler(ks, time.Hour)
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch atomic.AddInt32(&fetches, 1) {
+			case 1:
+			case 2:
+				<-gate
+			default:
+				if atomic.LoadInt32(&down) == 1 {
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+			}
+			h.ServeHTTP(w, r)
+		}))
+		defer srv.Close()
+
+		const ttl = 50 * time.Millisecond
+		remote := jwks.NewRemote(srv.URL, jwks.RemoteConfig{TTL: ttl, MinRefresh: time.Millisecond, MaxStale: 4 * ttl})
+		if _, _, err := remote.VerifyingKey("ec"); err != nil {
+			t.Fatalf("\t%s\tShould be able to fetch the key set: %v", failed, err)
+		}
+
+		testID := 0
+		t.Logf("\tTest %d:\tWhen the keys expired and the refresh is slow.", testID)
+		{
+			time.Sleep(2 * ttl)
+
+			looked := make(chan error)
+			go func() {
+				_, _, err := remote.VerifyingKey("ec")
+				looked <- err
+			}()
+
+			select {
+			case err := <-looked:
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould use the expired keys : %v", failed, testID, err)
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("\t%s\tTest %d:\tShould not wait for the refresh.", failed, testID)
+			}
+			close(gate)
+			t.Logf("\t%s\tTest %d:\tShould not wait for the refresh.", success, testID)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tWhen refreshes keep failing.", testID)
+		{
+			for atomic.LoadInt32(&fetches) < 2 {
+				time.Sleep(time.Millisecond)
+			}
+			time.Sleep(ttl / 2)
+			atomic.StoreInt32(&down, 1)
+			time.Sleep(2 * ttl)
+
+			if _, _, err := remote.VerifyingKey("ec"); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould keep using the keys for up to MaxStale : %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould keep using the keys for up to MaxStale.", success, testID)
+
+			time.Sleep(6 * ttl)
+			if _, _, err := remote.VerifyingKey("ec"); err == nil {
+				t.Fatalf("\t%s\tTest %d:\tShould drop the keys after MaxStale.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould drop the keys after MaxStale.", success, testID)
+		}
+	}
+}
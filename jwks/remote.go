@@ -0,0 +1,200 @@
+package jwks
+
+import (
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gitamped/seed/auth"
+)
+
+// RemoteConfig configures a Remote key set.
+type RemoteConfig struct {
+	// Client fetches the key set. Default: a client with a 10s timeout
+	Client *http.Client
+	// TTL is how long fetched keys are used before refreshing. Default: 1h
+	TTL time.Duration
+	// MinRefresh is the minimum time between fetches. It stops tokens with
+	// unknown kids from flooding the key set url. Default: 1m
+	MinRefresh time.Duration
+	// MaxStale is how long keys are used past TTL while refreshes fail,
+	// after which they are dropped. Default: 24h
+	MaxStale time.Duration
+}
+
+// Remote implements the auth.PublicKeyLookup interface by fetching a JWKS
+// document from a url, for services that only verify tokens.
+// Example: auth.NewVerifier(jwks.NewRemote(url, jwks.RemoteConfig{}), "RS256")
+type Remote struct {
+	url string
+	cfg RemoteConfig
+
+	mu          sync.RWMutex
+	keys        map[string]remoteKey
+	fetched     time.Time
+	lastAttempt time.Time
+	inflight    *fetchCall
+}
+
+// remoteKey is a decoded key from the remote key set.
+type remoteKey struct {
+	pub crypto.PublicKey
+	alg string
+}
+
+// fetchCall is a fetch of the key set in flight. done is closed once err is
+// set.
+type fetchCall struct {
+	done chan struct{}
+	err  error
+}
+
+// NewRemote constructs a Remote key set for the url. Keys are fetched on
+// first use.
+func NewRemote(url string, cfg RemoteConfig) *Remote {
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = time.Hour
+	}
+	if cfg.MinRefresh <= 0 {
+		cfg.MinRefresh = time.Minute
+	}
+	if cfg.MaxStale <= 0 {
+		cfg.MaxStale = 24 * time.Hour
+	}
+
+	return &Remote{
+		url:  url,
+		cfg:  cfg,
+		keys: make(map[string]remoteKey),
+	}
+}
+
+// VerifyingKey implements the auth.PublicKeyLookup interface. Expired
+// keys are refreshed in the background, so lookups of known kids do not
+// wait for the key set url; they keep using the previous keys while
+// refreshes fail, for up to MaxStale. Unknown kids wait for a refresh, at
+// most once per MinRefresh.
+func (r *Remote) VerifyingKey(kid string) (crypto.PublicKey, string, error) {
+	k, found, age := r.lookup(kid)
+
+	switch {
+	case found && age > r.cfg.TTL:
+		go r.refresh(false, false)
+	case !found:
+		err := r.refresh(false, true)
+		if k, found, _ = r.lookup(kid); !found && err != nil {
+			return nil, "", err
+		}
+	}
+
+	if !found {
+		return nil, "", errors.New("kid lookup failed")
+	}
+	return k.pub, k.alg, nil
+}
+
+// lookup returns the key of the kid and the age of the key set. Keys older
+// than TTL plus MaxStale are not returned.
+func (r *Remote) lookup(kid string) (remoteKey, bool, time.Duration) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	age := time.Since(r.fetched)
+	if r.fetched.IsZero() || age > r.cfg.TTL+r.cfg.MaxStale {
+		return remoteKey{}, false, age
+	}
+	k, found := r.keys[kid]
+	return k, found, age
+}
+
+// Refresh fetches the key set now.
+func (r *Remote) Refresh() error {
+	return r.refresh(true, true)
+}
+
+// refresh fetches the key set unless one was fetched less than MinRefresh
+// ago. Only one fetch is in flight at a time; when wait is true a fetch in
+// flight is waited for, otherwise the caller continues with the current
+// keys.
+func (r *Remote) refresh(force, wait bool) error {
+	r.mu.Lock()
+	if call := r.inflight; call != nil {
+		r.mu.Unlock()
+		if !wait {
+			return nil
+		}
+		<-call.done
+		return call.err
+	}
+
+	now := time.Now()
+	if !force && now.Sub(r.lastAttempt) < r.cfg.MinRefresh {
+		r.mu.Unlock()
+		return nil
+	}
+	call := fetchCall{done: make(chan struct{})}
+	r.inflight = &call
+	r.lastAttempt = now
+	r.mu.Unlock()
+
+	keys, err := r.fetch()
+
+	r.mu.Lock()
+	if err == nil {
+		r.keys = keys
+		r.fetched = now
+	}
+	r.inflight = nil
+	r.mu.Unlock()
+
+	call.err = err
+	close(call.done)
+	return err
+}
+
+// fetch fetches and decodes the key set.
+func (r *Remote) fetch() (map[string]remoteKey, error) {
+	resp, err := r.cfg.Client.Get(r.url)
+	if err != nil {
+		return nil, fmt.Errorf("fetching key set: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching key set: status %d", resp.StatusCode)
+	}
+
+	// limit the key set to 1 megabyte.
+	var s Set
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1024*1024)).Decode(&s); err != nil {
+		return nil, fmt.Errorf("decoding key set: %w", err)
+	}
+
+	keys := make(map[string]remoteKey, len(s.Keys))
+	for _, k := range s.Keys {
+		if k.Kid == "" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		pub, err := k.PublicKey()
+		if err != nil {
+			continue
+		}
+		alg := k.Alg
+		if alg == "" {
+			if alg, err = auth.AlgorithmForKey(pub); err != nil {
+				continue
+			}
+		}
+		keys[k.Kid] = remoteKey{pub: pub, alg: alg}
+	}
+
+	return keys, nil
+}
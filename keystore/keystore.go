@@ -8,7 +8,10 @@ package keystore
 	Copyright (c) Ardan Labs
 */
 import (
+	"crypto"
 	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
 	"errors"
 	"fmt"
 	"io"
@@ -17,27 +20,37 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/gitamped/seed/auth"
 	"github.com/golang-jwt/jwt/v4"
 )
 
 // KeyStore represents an in memory store implementation of the
-// KeyLookup interface for use with the auth package.
+// KeyLookup and SignerLookup interfaces for use with the auth package.
 type KeyStore struct {
-	mu    sync.RWMutex
-	store map[string]*rsa.PrivateKey
+	mu      sync.RWMutex
+	store   map[string]*rsa.PrivateKey
+	signers map[string]signer
+}
+
+// signer is a non RSA private key and the algorithm it is used with.
+type signer struct {
+	key crypto.Signer
+	alg string
 }
 
 // New constructs an empty KeyStore ready for use.
 func New() *KeyStore {
 	return &KeyStore{
-		store: make(map[string]*rsa.PrivateKey),
+		store:   make(map[string]*rsa.PrivateKey),
+		signers: make(map[string]signer),
 	}
 }
 
 // NewMap constructs a KeyStore with an initial set of keys.
 func NewMap(store map[string]*rsa.PrivateKey) *KeyStore {
 	return &KeyStore{
-		store: store,
+		store:   store,
+		signers: make(map[string]signer),
 	}
 }
 
@@ -75,13 +88,19 @@ func NewFS(fsys fs.FS) (*KeyStore, error) {
 			return fmt.Errorf("reading auth private key: %w", err)
 		}
 
+		kid := strings.TrimSuffix(dirEntry.Name(), ".pem")
+
 		privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(privatePEM)
-		if err != nil {
-			return fmt.Errorf("parsing auth private key: %w", err)
+		if err == nil {
+			ks.store[kid] = privateKey
+			return nil
 		}
 
-		ks.store[strings.TrimSuffix(dirEntry.Name(), ".pem")] = privateKey
-		return nil
+		key, perr := parseSignerPEM(privatePEM)
+		if perr != nil {
+			return fmt.Errorf("parsing auth private key: %w", err)
+		}
+		return ks.AddSigner(key, kid)
 	}
 
 	if err := fs.WalkDir(fsys, ".", fn); err != nil {
@@ -91,20 +110,45 @@ func NewFS(fsys fs.FS) (*KeyStore, error) {
 	return ks, nil
 }
 
-// Add adds a private key and combination kid to the store.
+// Add adds a private key and combination kid to the store, replacing any
+// key with the kid.
 func (ks *KeyStore) Add(privateKey *rsa.PrivateKey, kid string) {
 	ks.mu.Lock()
 	defer ks.mu.Unlock()
 
+	delete(ks.signers, kid)
 	ks.store[kid] = privateKey
 }
 
+// AddSigner adds an ECDSA or Ed25519 private key and combination kid to the
+// store, replacing any key with the kid. The algorithm is derived from the
+// key.
+func (ks *KeyStore) AddSigner(key crypto.Signer, kid string) error {
+	alg, err := auth.AlgorithmForKey(key.Public())
+	if err != nil {
+		return err
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	if pk, ok := key.(*rsa.PrivateKey); ok {
+		delete(ks.signers, kid)
+		ks.store[kid] = pk
+		return nil
+	}
+	delete(ks.store, kid)
+	ks.signers[kid] = signer{key: key, alg: alg}
+	return nil
+}
+
 // Remove removes a private key and combination kid to the store.
 func (ks *KeyStore) Remove(kid string) {
 	ks.mu.Lock()
 	defer ks.mu.Unlock()
 
 	delete(ks.store, kid)
+	delete(ks.signers, kid)
 }
 
 // PrivateKey searches the key store for a given kid and returns
@@ -132,3 +176,69 @@ func (ks *KeyStore) PublicKey(kid string) (*rsa.PublicKey, error) {
 	}
 	return &privateKey.PublicKey, nil
 }
+
+// SigningKey searches the key store for a given kid and returns the
+// private key and its algorithm.
+func (ks *KeyStore) SigningKey(kid string) (crypto.Signer, string, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	if privateKey, found := ks.store[kid]; found {
+		return privateKey, "RS256", nil
+	}
+	if s, found := ks.signers[kid]; found {
+		return s.key, s.alg, nil
+	}
+	return nil, "", errors.New("kid lookup failed")
+}
+
+// VerifyingKey searches the key store for a given kid and returns the
+// public key and its algorithm.
+func (ks *KeyStore) VerifyingKey(kid string) (crypto.PublicKey, string, error) {
+	key, alg, err := ks.SigningKey(kid)
+	if err != nil {
+		return nil, "", err
+	}
+	return key.Public(), alg, nil
+}
+
+// parseSignerPEM parses a PKCS #8 or SEC 1 encoded private key.
+func parseSignerPEM(privatePEM []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(privatePEM)
+	if block == nil {
+		return nil, errors.New("key must be PEM encoded")
+	}
+
+	var key any
+	var err error
+	switch block.Type {
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported key type %T", key)
+	}
+	return s, nil
+}
+
+// KIDs returns the key ids of every key in the store.
+func (ks *KeyStore) KIDs() []string {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	kids := make([]string, 0, len(ks.store)+len(ks.signers))
+	for kid := range ks.store {
+		kids = append(kids, kid)
+	}
+	for kid := range ks.signers {
+		kids = append(kids, kid)
+	}
+	return kids
+}
@@ -6,6 +6,10 @@ package keystore_test
 	Copyright (c) Ardan Labs
 */
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
 	"embed" // Calls init function.
 	"testing"
 
@@ -47,3 +51,39 @@ func Test_Read(t *testing.T) {
 		}
 	}
 }
+
+func Test_Replace(t *testing.T) {
+	t.Log("Given the need to replace the key of a kid.")
+	{
+		rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+		ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+		ks := keystore.New()
+
+		testID := 0
+		t.Logf("\tTest %d:\tWhen an ECDSA key replaces an RSA key.", testID)
+		{
+			ks.Add(rsaKey, "kid")
+			if err := ks.AddSigner(ecKey, "kid"); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to add the key: %v", failed, testID, err)
+			}
+			if _, alg, err := ks.SigningKey("kid"); err != nil || alg != "ES256" {
+				t.Fatalf("\t%s\tTest %d:\tShould sign with the last key added : %s %v", failed, testID, alg, err)
+			}
+			if _, err := ks.PrivateKey("kid"); err == nil {
+				t.Fatalf("\t%s\tTest %d:\tShould drop the RSA key.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould sign with the last key added.", success, testID)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tWhen an RSA key replaces an ECDSA key.", testID)
+		{
+			ks.Add(rsaKey, "kid")
+			if _, alg, err := ks.SigningKey("kid"); err != nil || alg != "RS256" {
+				t.Fatalf("\t%s\tTest %d:\tShould sign with the last key added : %s %v", failed, testID, alg, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould sign with the last key added.", success, testID)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package keystore
+
+import (
+	"errors"
+	"sync"
+)
+
+// SecretStore represents an in memory store implementation of the
+// SecretLookup interface for HMAC use with the auth package.
+type SecretStore struct {
+	mu    sync.RWMutex
+	store map[string][]byte
+}
+
+// NewSecrets constructs an empty SecretStore ready for use.
+func NewSecrets() *SecretStore {
+	return &SecretStore{
+		store: make(map[string][]byte),
+	}
+}
+
+// NewSecretMap constructs a SecretStore with an initial set of secrets.
+func NewSecretMap(store map[string][]byte) *SecretStore {
+	return &SecretStore{
+		store: store,
+	}
+}
+
+// Add adds a secret and combination kid to the store. To rotate secrets add
+// the new secret, rotate the auth to its kid and remove the old secret once
+// tokens signed with it have expired.
+func (ss *SecretStore) Add(secret []byte, kid string) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	ss.store[kid] = append([]byte(nil), secret...)
+}
+
+// Remove removes a secret and combination kid from the store.
+func (ss *SecretStore) Remove(kid string) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	delete(ss.store, kid)
+}
+
+// Secret searches the store for a given kid and returns the secret.
+func (ss *SecretStore) Secret(kid string) ([]byte, error) {
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
+
+	secret, found := ss.store[kid]
+	if !found {
+		return nil, errors.New("kid lookup failed")
+	}
+	return secret, nil
+}
@@ -0,0 +1,12 @@
+package mid
+
+import (
+	"github.com/gitamped/seed/apikey"
+)
+
+// APIKeyMiddleware authenticates requests carrying an API key in the named
+// header and adds the claims of the key to the context. Default header:
+// X-API-Key
+func APIKeyMiddleware(s apikey.Store, header string) Middleware {
+	return ChainAuthMiddleware(APIKeyAuthenticator(s, header))
+}
@@ -7,28 +7,36 @@ import (
 	"github.com/gitamped/seed/auth"
 )
 
-func AuthMiddleware(a *auth.Auth) Middleware {
-	m := func(h http.HandlerFunc) http.HandlerFunc {
-		handler := func(w http.ResponseWriter, r *http.Request) {
-			// Expecting: bearer <token>
-			authStr := r.Header.Get("authorization")
+// TokenExtractor returns the token sent with the request, if any.
+type TokenExtractor func(r *http.Request) (string, bool)
 
-			// Parse the authorization header.
-			parts := strings.Split(authStr, " ")
-			if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
-				h.ServeHTTP(w, r)
-			} else {
-				// Validate the token is signed by us.
-				claims, err := a.ValidateToken(parts[1])
-				if err == nil {
-					// Add claims to the context, so they can be retrieved later.
-					ctx := auth.SetClaims(r.Context(), claims)
-					r = r.WithContext(ctx)
-				}
-				h.ServeHTTP(w, r)
-			}
+// BearerToken extracts the token from the authorization header.
+func BearerToken(r *http.Request) (string, bool) {
+	// Expecting: bearer <token>
+	authStr := r.Header.Get("authorization")
+
+	// Parse the authorization header.
+	parts := strings.Split(authStr, " ")
+	if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
+		return "", false
+	}
+	return parts[1], true
+}
+
+// CookieToken extracts the token from the named cookie. Cookies should be
+// httpOnly and used together with CSRFMiddleware.
+func CookieToken(name string) TokenExtractor {
+	return func(r *http.Request) (string, bool) {
+		c, err := r.Cookie(name)
+		if err != nil || c.Value == "" {
+			return "", false
 		}
-		return handler
+		return c.Value, true
 	}
-	return m
+}
+
+// AuthMiddleware validates the token of the request and adds its claims to
+// the context. Extractors are tried in order. Default: BearerToken
+func AuthMiddleware(a *auth.Auth, extract ...TokenExtractor) Middleware {
+	return ChainAuthMiddleware(BearerAuthenticator(a, extract...))
 }
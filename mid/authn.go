@@ -0,0 +1,243 @@
+package mid
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gitamped/seed/apikey"
+	"github.com/gitamped/seed/auth"
+	"github.com/gitamped/seed/introspect"
+	"github.com/gitamped/seed/mtls"
+	"github.com/gitamped/seed/session"
+)
+
+// These are the schemes recorded for authenticated requests.
+const (
+	SchemeBearer = "bearer"
+	SchemeAPIKey = "apikey"
+	SchemeBasic  = "basic"
+	SchemeMTLS   = "mtls"
+	// SchemeSession is recorded for session cookies.
+	SchemeSession = "session"
+	// SchemeIntrospection is recorded for opaque bearer tokens.
+	SchemeIntrospection = "introspection"
+)
+
+// Authenticator declares a method set of behavior for authenticating a
+// request with one scheme. Authenticate returns false if the request does
+// not carry credentials for the scheme.
+type Authenticator interface {
+	Scheme() string
+	Authenticate(r *http.Request) (auth.Claims, bool, error)
+}
+
+// CredentialStore declares a method set of behavior for checking a
+// username and password.
+type CredentialStore interface {
+	Verify(ctx context.Context, username, password string) (auth.Claims, error)
+}
+
+// ChainAuthMiddleware tries each authenticator in order and adds the claims
+// of the first that succeeds, and its scheme, to the context. If every
+// authenticator with credentials fails the first error is recorded.
+func ChainAuthMiddleware(authns ...Authenticator) Middleware {
+	m := func(h http.HandlerFunc) http.HandlerFunc {
+		handler := func(w http.ResponseWriter, r *http.Request) {
+			var firstErr error
+			for _, a := range authns {
+				claims, ok, err := a.Authenticate(r)
+				if !ok {
+					continue
+				}
+				if err != nil {
+					if firstErr == nil {
+						firstErr = err
+					}
+					continue
+				}
+
+				// Add claims to the context, so they can be retrieved later.
+				ctx := auth.SetClaims(r.Context(), claims)
+				ctx = auth.SetScheme(ctx, a.Scheme())
+				h.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+
+			if firstErr != nil {
+				// Record why, so protected endpoints can tell the caller.
+				r = r.WithContext(auth.SetAuthError(r.Context(), firstErr))
+			}
+			h.ServeHTTP(w, r)
+		}
+		return handler
+	}
+	return m
+}
+
+// =============================================================================
+
+type bearerAuthenticator struct {
+	auth    *auth.Auth
+	extract []TokenExtractor
+}
+
+// BearerAuthenticator authenticates requests with a JWT. Extractors are
+// tried in order. Default: BearerToken
+func BearerAuthenticator(a *auth.Auth, extract ...TokenExtractor) Authenticator {
+	if len(extract) == 0 {
+		extract = []TokenExtractor{BearerToken}
+	}
+	return bearerAuthenticator{auth: a, extract: extract}
+}
+
+// Scheme implements the Authenticator interface.
+func (bearerAuthenticator) Scheme() string {
+	return SchemeBearer
+}
+
+// Authenticate implements the Authenticator interface.
+func (ba bearerAuthenticator) Authenticate(r *http.Request) (auth.Claims, bool, error) {
+	for _, e := range ba.extract {
+		token, ok := e(r)
+		if !ok {
+			continue
+		}
+
+		// Validate the token is signed by us.
+		claims, err := ba.auth.ValidateToken(token)
+		return claims, true, err
+	}
+	return auth.Claims{}, false, nil
+}
+
+type introspectionAuthenticator struct {
+	client  *introspect.Client
+	extract []TokenExtractor
+}
+
+// IntrospectionAuthenticator authenticates requests with an opaque token
+// checked by the introspection endpoint of the client. Extractors are tried
+// in order. Default: BearerToken
+func IntrospectionAuthenticator(c *introspect.Client, extract ...TokenExtractor) Authenticator {
+	if len(extract) == 0 {
+		extract = []TokenExtractor{BearerToken}
+	}
+	return introspectionAuthenticator{client: c, extract: extract}
+}
+
+// Scheme implements the Authenticator interface.
+func (introspectionAuthenticator) Scheme() string {
+	return SchemeIntrospection
+}
+
+// Authenticate implements the Authenticator interface.
+func (ia introspectionAuthenticator) Authenticate(r *http.Request) (auth.Claims, bool, error) {
+	for _, e := range ia.extract {
+		token, ok := e(r)
+		if !ok {
+			continue
+		}
+		claims, err := ia.client.Authenticate(r.Context(), token)
+		return claims, true, err
+	}
+	return auth.Claims{}, false, nil
+}
+
+type apiKeyAuthenticator struct {
+	store  apikey.Store
+	header string
+}
+
+// APIKeyAuthenticator authenticates requests with an API key in the named
+// header. Default header: X-API-Key
+func APIKeyAuthenticator(s apikey.Store, header string) Authenticator {
+	if header == "" {
+		header = "X-API-Key"
+	}
+	return apiKeyAuthenticator{store: s, header: header}
+}
+
+// Scheme implements the Authenticator interface.
+func (apiKeyAuthenticator) Scheme() string {
+	return SchemeAPIKey
+}
+
+// Authenticate implements the Authenticator interface.
+func (ka apiKeyAuthenticator) Authenticate(r *http.Request) (auth.Claims, bool, error) {
+	key := r.Header.Get(ka.header)
+	if key == "" {
+		return auth.Claims{}, false, nil
+	}
+	claims, err := apikey.Authenticate(r.Context(), ka.store, key)
+	return claims, true, err
+}
+
+type basicAuthenticator struct {
+	store CredentialStore
+}
+
+// BasicAuthenticator authenticates requests with HTTP basic auth against
+// the credential store.
+func BasicAuthenticator(s CredentialStore) Authenticator {
+	return basicAuthenticator{store: s}
+}
+
+// Scheme implements the Authenticator interface.
+func (basicAuthenticator) Scheme() string {
+	return SchemeBasic
+}
+
+// Authenticate implements the Authenticator interface.
+func (ba basicAuthenticator) Authenticate(r *http.Request) (auth.Claims, bool, error) {
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return auth.Claims{}, false, nil
+	}
+	claims, err := ba.store.Verify(r.Context(), username, password)
+	return claims, true, err
+}
+
+type mtlsAuthenticator struct {
+	mapper *mtls.Mapper
+}
+
+// MTLSAuthenticator authenticates requests by their verified client
+// certificate.
+func MTLSAuthenticator(m *mtls.Mapper) Authenticator {
+	return mtlsAuthenticator{mapper: m}
+}
+
+// Scheme implements the Authenticator interface.
+func (mtlsAuthenticator) Scheme() string {
+	return SchemeMTLS
+}
+
+// Authenticate implements the Authenticator interface.
+func (ma mtlsAuthenticator) Authenticate(r *http.Request) (auth.Claims, bool, error) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return auth.Claims{}, false, nil
+	}
+	claims, err := ma.mapper.Claims(r.TLS.VerifiedChains[0][0])
+	return claims, true, err
+}
+
+type sessionAuthenticator struct {
+	manager *session.Manager
+}
+
+// SessionAuthenticator authenticates requests with a session cookie issued
+// by the manager.
+func SessionAuthenticator(m *session.Manager) Authenticator {
+	return sessionAuthenticator{manager: m}
+}
+
+// Scheme implements the Authenticator interface.
+func (sessionAuthenticator) Scheme() string {
+	return SchemeSession
+}
+
+// Authenticate implements the Authenticator interface.
+func (sa sessionAuthenticator) Authenticate(r *http.Request) (auth.Claims, bool, error) {
+	s, ok, err := sa.manager.Load(r)
+	return s.Claims, ok, err
+}
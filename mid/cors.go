@@ -0,0 +1,166 @@
+package mid
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// CORS configures cross-origin resource sharing for browser clients.
+type CORS struct {
+	// AllowedOrigins lists the origins allowed to call the server. An
+	// origin may be "*" or contain a wildcard subdomain,
+	// e.g. "https://*.example.com". Origins only allowed by "*" are never
+	// allowed credentials.
+	AllowedOrigins []string
+	// AllowedMethods lists the methods allowed. Default: POST
+	AllowedMethods []string
+	// AllowedHeaders lists the request headers allowed.
+	// Default: Content-Type, Authorization
+	AllowedHeaders []string
+	// ExposedHeaders lists the response headers browsers may read.
+	ExposedHeaders []string
+	// AllowCredentials allows cookies and authorization headers to be sent.
+	AllowCredentials bool
+	// MaxAge is how long, in seconds, preflight results may be cached.
+	MaxAge int
+}
+
+// Handle sets the CORS headers for the request. It responds to preflight
+// requests and returns true if the request has been handled.
+func (c *CORS) Handle(w http.ResponseWriter, r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+	if origin == "" {
+		return false
+	}
+	w.Header().Add("Vary", "Origin")
+
+	allowed, explicit := c.originAllowed(origin)
+	if !allowed {
+		if preflight {
+			w.WriteHeader(http.StatusForbidden)
+		}
+		return preflight
+	}
+
+	if !preflight {
+		c.allowOrigin(w, origin, explicit)
+		if len(c.ExposedHeaders) > 0 {
+			w.Header().Set("Access-Control-Expose-Headers", strings.Join(c.ExposedHeaders, ", "))
+		}
+		return false
+	}
+
+	w.Header().Add("Vary", "Access-Control-Request-Method")
+	w.Header().Add("Vary", "Access-Control-Request-Headers")
+
+	methods := c.methods()
+	if !containsFold(methods, r.Header.Get("Access-Control-Request-Method")) {
+		w.WriteHeader(http.StatusForbidden)
+		return true
+	}
+
+	headers := c.headers()
+	for _, h := range strings.Split(r.Header.Get("Access-Control-Request-Headers"), ",") {
+		if h = strings.TrimSpace(h); h != "" && !containsFold(headers, h) {
+			w.WriteHeader(http.StatusForbidden)
+			return true
+		}
+	}
+
+	c.allowOrigin(w, origin, explicit)
+	w.Header().Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+	w.Header().Set("Access-Control-Allow-Headers", strings.Join(headers, ", "))
+	if c.MaxAge > 0 {
+		w.Header().Set("Access-Control-Max-Age", strconv.Itoa(c.MaxAge))
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return true
+}
+
+// allowOrigin sets the allowed origin and credentials headers. Credentials
+// are only allowed for origins that were explicitly allowed, otherwise any
+// site could make credentialed calls.
+func (c *CORS) allowOrigin(w http.ResponseWriter, origin string, explicit bool) {
+	if !explicit {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		return
+	}
+	w.Header().Set("Access-Control-Allow-Origin", origin)
+	if c.AllowCredentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+// originAllowed reports whether the origin matches an allowed origin, and
+// whether it matched other than by "*".
+func (c *CORS) originAllowed(origin string) (bool, bool) {
+	origin = strings.ToLower(origin)
+	wildcard := false
+	for _, allowed := range c.AllowedOrigins {
+		allowed = strings.ToLower(allowed)
+		if allowed == "*" {
+			wildcard = true
+			continue
+		}
+		if allowed == origin {
+			return true, true
+		}
+
+		// Match wildcard subdomains, e.g. https://*.example.com
+		if i := strings.Index(allowed, "*."); i >= 0 {
+			prefix, suffix := allowed[:i], allowed[i+1:]
+			if strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) {
+				sub := origin[len(prefix) : len(origin)-len(suffix)]
+				if sub != "" && !strings.ContainsAny(sub, "/:") {
+					return true, true
+				}
+			}
+		}
+	}
+	return wildcard, false
+}
+
+// methods returns the allowed methods.
+func (c *CORS) methods() []string {
+	if len(c.AllowedMethods) == 0 {
+		return []string{http.MethodPost}
+	}
+	return c.AllowedMethods
+}
+
+// headers returns the allowed request headers.
+func (c *CORS) headers() []string {
+	if len(c.AllowedHeaders) == 0 {
+		return []string{"Content-Type", "Authorization"}
+	}
+	return c.AllowedHeaders
+}
+
+// CORSMiddleware sets the CORS headers for requests and responds to
+// preflight requests. Use Server.CORS to handle preflight requests before
+// the server rejects non POST methods.
+func CORSMiddleware(c *CORS) Middleware {
+	m := func(h http.HandlerFunc) http.HandlerFunc {
+		handler := func(w http.ResponseWriter, r *http.Request) {
+			if c.Handle(w, r) {
+				return
+			}
+			h.ServeHTTP(w, r)
+		}
+		return handler
+	}
+	return m
+}
+
+// containsFold reports whether s contains v, ignoring case.
+func containsFold(s []string, v string) bool {
+	for _, e := range s {
+		if strings.EqualFold(e, v) {
+			return true
+		}
+	}
+	return false
+}
@@ -4,6 +4,8 @@ import (
 	"log"
 	"net/http"
 	"os"
+
+	"github.com/gitamped/seed/values"
 )
 
 // Basic logging middleware
@@ -12,5 +14,23 @@ func LogMiddleware(h http.HandlerFunc) http.HandlerFunc {
 		log.SetOutput(os.Stdout) // logs go to Stderr by default
 		log.Println(r.Method, r.URL)
 		h.ServeHTTP(w, r) // call ServeHTTP on the original handler
+
+		v, err := values.GetValues(r.Context())
+		if err != nil {
+			return
+		}
+
+		// Record who acted, and for whom when impersonating, for auditing.
+		switch {
+		case v.Actor != v.Subject:
+			log.Println(r.Method, r.URL, "actor:", v.Actor, "on behalf of:", v.Subject)
+		case v.Actor != "":
+			log.Println(r.Method, r.URL, "actor:", v.Actor)
+		}
+
+		// Record why a request was denied for auditing.
+		if v.DenyReason != "" {
+			log.Println(r.Method, r.URL, "denied:", v.DenyReason)
+		}
 	})
 }
@@ -0,0 +1,11 @@
+package mid
+
+import (
+	"github.com/gitamped/seed/mtls"
+)
+
+// MTLSMiddleware adds the claims of the verified client certificate to the
+// context. Requests without a verified certificate are passed through.
+func MTLSMiddleware(m *mtls.Mapper) Middleware {
+	return ChainAuthMiddleware(MTLSAuthenticator(m))
+}
@@ -0,0 +1,49 @@
+package mid
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gitamped/seed/ratelimit"
+)
+
+// RateLimitMiddleware rejects requests over their limit with 429 Too Many
+// Requests. RateLimit-* headers are set on every limited route.
+func RateLimitMiddleware(l *ratelimit.Limiter) Middleware {
+	m := func(h http.HandlerFunc) http.HandlerFunc {
+		handler := func(w http.ResponseWriter, r *http.Request) {
+			res, ok, err := l.Take(r)
+			if err != nil {
+				// Fail open so a store outage does not take down the api.
+				log.Printf("rate limit: %s\n", err)
+				h.ServeHTTP(w, r)
+				return
+			}
+			if !ok {
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			w.Header().Set("RateLimit-Reset", ceilSeconds(res.Reset))
+
+			if !res.Allowed {
+				w.Header().Set("Retry-After", ceilSeconds(res.RetryAfter))
+				http.Error(w, "429 Too Many Requests", http.StatusTooManyRequests)
+				return
+			}
+			h.ServeHTTP(w, r)
+		}
+		return handler
+	}
+	return m
+}
+
+// ceilSeconds formats d as whole seconds, rounded up.
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,12 @@
+package mid
+
+import (
+	"github.com/gitamped/seed/session"
+)
+
+// SessionMiddleware adds the claims of the session of the request to the
+// context. Cookies are sent by browsers automatically, so state changing
+// endpoints should also be protected by CSRFMiddleware.
+func SessionMiddleware(m *session.Manager) Middleware {
+	return ChainAuthMiddleware(SessionAuthenticator(m))
+}
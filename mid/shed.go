@@ -0,0 +1,26 @@
+package mid
+
+import (
+	"net/http"
+
+	"github.com/gitamped/seed/concurrency"
+)
+
+// LoadShedMiddleware rejects requests with 503 Service Unavailable when
+// no concurrency slot becomes available.
+func LoadShedMiddleware(s *concurrency.Shedder) Middleware {
+	m := func(h http.HandlerFunc) http.HandlerFunc {
+		handler := func(w http.ResponseWriter, r *http.Request) {
+			release, err := s.Acquire(r.Context(), r.URL.Path)
+			if err != nil {
+				w.Header().Set("Retry-After", "1")
+				http.Error(w, "503 Service Unavailable", http.StatusServiceUnavailable)
+				return
+			}
+			defer release()
+			h.ServeHTTP(w, r)
+		}
+		return handler
+	}
+	return m
+}
@@ -0,0 +1,143 @@
+// Package mtls maps verified client certificates to auth.Claims for
+// service-to-service calls authenticated by mutual TLS.
+package mtls
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gitamped/seed/auth"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// ErrNoMatch is returned when no rule matches the certificate.
+var ErrNoMatch = errors.New("certificate does not match any rule")
+
+// SubjectPrefix prefixes the subject of certificate claims, so services
+// can not be taken for users with the same name, e.g. by ownership
+// policies.
+const SubjectPrefix = "mtls:"
+
+// Rule grants Roles to certificates matching every non empty field. Fields
+// may end in "*" to match a prefix, e.g. "spiffe://example.org/ns/prod/*".
+type Rule struct {
+	// CommonName matches the subject common name.
+	CommonName string
+	// DNSName matches any DNS subject alternative name.
+	DNSName string
+	// URI matches any URI subject alternative name, such as a SPIFFE ID.
+	URI   string
+	Roles []string
+}
+
+// matches reports whether the certificate matches the rule.
+func (r Rule) matches(cert *x509.Certificate) bool {
+	if r.CommonName == "" && r.DNSName == "" && r.URI == "" {
+		return false
+	}
+	if r.CommonName != "" && !match(r.CommonName, cert.Subject.CommonName) {
+		return false
+	}
+	if r.DNSName != "" && !matchAny(r.DNSName, cert.DNSNames) {
+		return false
+	}
+	if r.URI != "" {
+		uris := make([]string, 0, len(cert.URIs))
+		for _, u := range cert.URIs {
+			uris = append(uris, u.String())
+		}
+		if !matchAny(r.URI, uris) {
+			return false
+		}
+	}
+	return true
+}
+
+// Mapper maps certificates to claims.
+type Mapper struct {
+	Rules []Rule
+}
+
+// Claims returns the claims for a verified certificate. The subject is the
+// SPIFFE ID of the certificate, or its common name, prefixed with
+// SubjectPrefix, and the roles are those of every matching rule.
+func (m *Mapper) Claims(cert *x509.Certificate) (auth.Claims, error) {
+	var roles []string
+	matched := false
+	for _, r := range m.Rules {
+		if r.matches(cert) {
+			matched = true
+			roles = append(roles, r.Roles...)
+		}
+	}
+	if !matched {
+		return auth.Claims{}, ErrNoMatch
+	}
+
+	subject := cert.Subject.CommonName
+	for _, u := range cert.URIs {
+		if u.Scheme == "spiffe" {
+			subject = u.String()
+			break
+		}
+	}
+
+	claims := auth.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   SubjectPrefix + subject,
+			Issuer:    cert.Issuer.CommonName,
+			ExpiresAt: jwt.NewNumericDate(cert.NotAfter),
+		},
+		Roles: roles,
+	}
+
+	return claims, nil
+}
+
+// ServerConfig builds a tls.Config requiring clients to present a
+// certificate signed by a CA in the PEM bundle.
+func ServerConfig(caPEM []byte) (*tls.Config, error) {
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return nil, errors.New("no certificates found in CA bundle")
+	}
+
+	cfg := tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  pool,
+		MinVersion: tls.VersionTLS12,
+	}
+
+	return &cfg, nil
+}
+
+// LoadServerConfig builds a tls.Config from the CA bundle file.
+func LoadServerConfig(caFile string) (*tls.Config, error) {
+	b, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("reading CA bundle: %w", err)
+	}
+	return ServerConfig(b)
+}
+
+// match reports whether v matches the pattern.
+func match(pattern, v string) bool {
+	if strings.HasSuffix(pattern, "*") {
+		return strings.HasPrefix(v, strings.TrimSuffix(pattern, "*"))
+	}
+	return pattern == v
+}
+
+// matchAny reports whether any value matches the pattern.
+func matchAny(pattern string, vs []string) bool {
+	for _, v := range vs {
+		if match(pattern, v) {
+			return true
+		}
+	}
+	return false
+}
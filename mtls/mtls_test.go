@@ -0,0 +1,121 @@
+package mtls_test
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gitamped/seed/auth"
+	"github.com/gitamped/seed/mid"
+	"github.com/gitamped/seed/mtls"
+	"github.com/gitamped/seed/server"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+func Test_MTLS(t *testing.T) {
+	t.Log("Given the need to authenticate services by client certificate.")
+	{
+		caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		caTmpl := &x509.Certificate{
+			SerialNumber:          big.NewInt(1),
+			Subject:               pkix.Name{CommonName: "seed test ca"},
+			NotBefore:             time.Now().Add(-time.Hour),
+			NotAfter:              time.Now().Add(time.Hour),
+			IsCA:                  true,
+			KeyUsage:              x509.KeyUsageCertSign,
+			BasicConstraintsValid: true,
+		}
+		caDER, _ := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+		caCert, _ := x509.ParseCertificate(caDER)
+		caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER})
+
+		clientCert := func(spiffeID string) tls.Certificate {
+			key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			u, _ := url.Parse(spiffeID)
+			tmpl := &x509.Certificate{
+				SerialNumber: big.NewInt(time.Now().UnixNano()),
+				Subject:      pkix.Name{CommonName: "client"},
+				URIs:         []*url.URL{u},
+				NotBefore:    time.Now().Add(-time.Hour),
+				NotAfter:     time.Now().Add(time.Hour),
+				KeyUsage:     x509.KeyUsageDigitalSignature,
+				ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+			}
+			der, _ := x509.CreateCertificate(rand.Reader, tmpl, caCert, &key.PublicKey, caKey)
+			return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+		}
+
+		m := &mtls.Mapper{Rules: []mtls.Rule{
+			{URI: "spiffe://seed.test/ns/prod/*", Roles: []string{auth.RoleUser}},
+		}}
+		mw := append([]mid.Middleware{mid.MTLSMiddleware(m)}, mid.CommonMiddleware...)
+		s := server.NewServer(mw)
+		s.Register("InternalService", "Call", server.RPCEndpoint{
+			Roles: []string{auth.RoleUser},
+			Handler: func(g server.GenericRequest, b []byte) (any, error) {
+				return struct{ Subject string }{g.Claims.Subject}, nil
+			},
+		})
+
+		cfg, err := mtls.ServerConfig(caPEM)
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to build the tls config: %v", failed, err)
+		}
+		srv := httptest.NewUnstartedServer(s)
+		srv.TLS = cfg
+		srv.StartTLS()
+		defer srv.Close()
+
+		ttable := []struct {
+			TestTitle          string
+			SpiffeID           string
+			ExpectedStatusCode int
+		}{
+			{"When a production service calls", "spiffe://seed.test/ns/prod/billing", http.StatusOK},
+			{"When a staging service calls", "spiffe://seed.test/ns/staging/billing", http.StatusUnauthorized},
+		}
+
+		for testID, td := range ttable {
+			t.Logf("\tTest %d:\t%s.", testID, td.TestTitle)
+			{
+				transport := srv.Client().Transport.(*http.Transport).Clone()
+				transport.TLSClientConfig.Certificates = []tls.Certificate{clientCert(td.SpiffeID)}
+				client := http.Client{Transport: transport}
+
+				resp, err := client.Post(srv.URL+"/v1/InternalService.Call", "application/json", bytes.NewBufferString(`{}`))
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to call the server: %v", failed, testID, err)
+				}
+				var body struct{ Subject string }
+				json.NewDecoder(resp.Body).Decode(&body)
+				resp.Body.Close()
+
+				if resp.StatusCode != td.ExpectedStatusCode {
+					t.Fatalf("\t%s\tTest %d:\tShould receive a status code of %d for the response : %v", failed, testID, td.ExpectedStatusCode, resp.StatusCode)
+				}
+				t.Logf("\t%s\tTest %d:\tShould receive a status code of %d for the response.", success, testID, td.ExpectedStatusCode)
+
+				if resp.StatusCode == http.StatusOK && body.Subject != "mtls:"+td.SpiffeID {
+					t.Fatalf("\t%s\tTest %d:\tShould prefix the subject : %q", failed, testID, body.Subject)
+				}
+			}
+		}
+	}
+}
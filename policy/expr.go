@@ -0,0 +1,318 @@
+package policy
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/gitamped/seed/auth"
+)
+
+// node is a compiled expression.
+type node interface {
+	eval(env map[string]any) any
+}
+
+// compile parses an expression such as:
+//
+//	request.owner == claims.sub && !("ADMIN" in claims.roles)
+//
+// Supported are attribute paths, string, number and boolean literals, the
+// operators ==, !=, in, &&, || and !, and parentheses.
+func compile(src string) (node, error) {
+	toks, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+
+	p := parser{toks: toks}
+	n, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(p.toks) {
+		return nil, fmt.Errorf("unexpected %q", p.toks[p.pos].text)
+	}
+	return n, nil
+}
+
+// =============================================================================
+
+type tokenKind int
+
+const (
+	tokIdent tokenKind = iota
+	tokString
+	tokNumber
+	tokOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+// tokenize splits the expression into tokens.
+func tokenize(src string) ([]token, error) {
+	var toks []token
+	rs := []rune(src)
+
+	for i := 0; i < len(rs); {
+		r := rs[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+
+		case r == '"' || r == '\'':
+			j := i + 1
+			for j < len(rs) && rs[j] != r {
+				j++
+			}
+			if j == len(rs) {
+				return nil, fmt.Errorf("unterminated string at %d", i)
+			}
+			toks = append(toks, token{kind: tokString, text: string(rs[i+1 : j])})
+			i = j + 1
+
+		case unicode.IsDigit(r) || (r == '-' && i+1 < len(rs) && unicode.IsDigit(rs[i+1])):
+			j := i + 1
+			for j < len(rs) && (unicode.IsDigit(rs[j]) || rs[j] == '.') {
+				j++
+			}
+			toks = append(toks, token{kind: tokNumber, text: string(rs[i:j])})
+			i = j
+
+		case unicode.IsLetter(r) || r == '_':
+			j := i + 1
+			for j < len(rs) && (unicode.IsLetter(rs[j]) || unicode.IsDigit(rs[j]) || rs[j] == '_' || rs[j] == '.') {
+				j++
+			}
+			toks = append(toks, token{kind: tokIdent, text: string(rs[i:j])})
+			i = j
+
+		default:
+			op := ""
+			for _, o := range []string{"==", "!=", "&&", "||", "!", "(", ")"} {
+				if strings.HasPrefix(string(rs[i:]), o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected %q at %d", r, i)
+			}
+			toks = append(toks, token{kind: tokOp, text: op})
+			i += len(op)
+		}
+	}
+
+	return toks, nil
+}
+
+// =============================================================================
+
+// parser is a recursive descent parser over the tokens.
+type parser struct {
+	toks []token
+	pos  int
+}
+
+// accept consumes the next token if it is the operator op.
+func (p *parser) accept(op string) bool {
+	if p.pos < len(p.toks) && (p.toks[p.pos].kind == tokOp || p.toks[p.pos].kind == tokIdent) && p.toks[p.pos].text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) or() (node, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") {
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = logical{op: "||", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) and() (node, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") {
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = logical{op: "&&", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) unary() (node, error) {
+	if p.accept("!") {
+		n, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return not{n}, nil
+	}
+	return p.comparison()
+}
+
+func (p *parser) comparison() (node, error) {
+	left, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range []string{"==", "!=", "in"} {
+		if p.accept(op) {
+			right, err := p.primary()
+			if err != nil {
+				return nil, err
+			}
+			return compare{op: op, left: left, right: right}, nil
+		}
+	}
+	return left, nil
+}
+
+func (p *parser) primary() (node, error) {
+	if p.pos >= len(p.toks) {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+
+	if p.accept("(") {
+		n, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(")") {
+			return nil, fmt.Errorf("missing )")
+		}
+		return n, nil
+	}
+
+	t := p.toks[p.pos]
+	p.pos++
+
+	switch t.kind {
+	case tokString:
+		return literal{t.text}, nil
+	case tokNumber:
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", t.text)
+		}
+		return literal{f}, nil
+	case tokIdent:
+		switch t.text {
+		case "true":
+			return literal{true}, nil
+		case "false":
+			return literal{false}, nil
+		case "in":
+			return nil, fmt.Errorf("unexpected in")
+		}
+		return path(strings.Split(t.text, ".")), nil
+	}
+
+	return nil, fmt.Errorf("unexpected %q", t.text)
+}
+
+// =============================================================================
+
+type literal struct {
+	v any
+}
+
+func (l literal) eval(env map[string]any) any {
+	return l.v
+}
+
+// path looks up an attribute, e.g. claims.sub. Missing attributes are nil.
+type path []string
+
+func (p path) eval(env map[string]any) any {
+	var v any = env
+	for _, name := range p {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		v, _ = auth.Field(m, name)
+	}
+	return v
+}
+
+type not struct {
+	n node
+}
+
+func (n not) eval(env map[string]any) any {
+	return !truthy(n.n.eval(env))
+}
+
+type logical struct {
+	op          string
+	left, right node
+}
+
+func (l logical) eval(env map[string]any) any {
+	if l.op == "&&" {
+		return truthy(l.left.eval(env)) && truthy(l.right.eval(env))
+	}
+	return truthy(l.left.eval(env)) || truthy(l.right.eval(env))
+}
+
+type compare struct {
+	op          string
+	left, right node
+}
+
+func (c compare) eval(env map[string]any) any {
+	left, right := c.left.eval(env), c.right.eval(env)
+
+	switch c.op {
+	case "==":
+		return equal(left, right)
+	case "!=":
+		return !equal(left, right)
+	}
+
+	// in
+	list, ok := right.([]any)
+	if !ok {
+		return false
+	}
+	for _, v := range list {
+		if equal(left, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// equal compares two values. Missing values are never equal, so a rule
+// comparing two absent attributes does not match.
+func equal(a, b any) bool {
+	if a == nil || b == nil {
+		return false
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// truthy reports whether v is the boolean true.
+func truthy(v any) bool {
+	b, ok := v.(bool)
+	return ok && b
+}
@@ -0,0 +1,148 @@
+// Package policy provides an auth.Authorizer evaluating attribute based
+// rules, loaded from a file, against the caller's claims and the request.
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gitamped/seed/auth"
+)
+
+// These are the effects of a rule.
+const (
+	Allow = "allow"
+	Deny  = "deny"
+)
+
+// Rule applies Effect to requests for Route when Condition is true. Route
+// may end in "*" to match a prefix. An empty Condition is always true.
+//
+// Conditions may refer to route, claims (e.g. claims.sub, claims.roles)
+// and request (e.g. request.owner) and support ==, !=, in, &&, || and !.
+type Rule struct {
+	Route     string `json:"route"`
+	Effect    string `json:"effect"`
+	Condition string `json:"condition"`
+}
+
+// Policy is the file representation of rules.
+type Policy struct {
+	Rules []Rule `json:"rules"`
+	// Default is the effect when no rule matches. Default: deny
+	Default string `json:"default"`
+}
+
+// Engine implements the auth.Authorizer interface. The first rule that
+// matches the route and whose condition is true decides.
+type Engine struct {
+	rules []compiled
+	allow bool
+}
+
+// compiled is a rule with its parsed condition.
+type compiled struct {
+	Rule
+	cond node
+}
+
+// New compiles the policy into an Engine.
+func New(p Policy) (*Engine, error) {
+	e := Engine{
+		rules: make([]compiled, 0, len(p.Rules)),
+	}
+
+	switch p.Default {
+	case Allow:
+		e.allow = true
+	case Deny, "":
+	default:
+		return nil, fmt.Errorf("invalid default effect %q", p.Default)
+	}
+
+	for i, r := range p.Rules {
+		if r.Effect != Allow && r.Effect != Deny {
+			return nil, fmt.Errorf("rule %d: invalid effect %q", i, r.Effect)
+		}
+
+		c := compiled{Rule: r, cond: literal{true}}
+		if strings.TrimSpace(r.Condition) != "" {
+			n, err := compile(r.Condition)
+			if err != nil {
+				return nil, fmt.Errorf("rule %d: compiling condition: %w", i, err)
+			}
+			c.cond = n
+		}
+		e.rules = append(e.rules, c)
+	}
+
+	return &e, nil
+}
+
+// LoadFile reads a JSON policy file and compiles it into an Engine.
+func LoadFile(path string) (*Engine, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading policy: %w", err)
+	}
+
+	var p Policy
+	if err := json.Unmarshal(b, &p); err != nil {
+		return nil, fmt.Errorf("decoding policy: %w", err)
+	}
+
+	return New(p)
+}
+
+// Authorize implements the auth.Authorizer interface.
+func (e *Engine) Authorize(ctx context.Context, in auth.AuthzInput) (auth.Decision, error) {
+	claims, err := toMap(in.Claims)
+	if err != nil {
+		return auth.Decision{}, fmt.Errorf("encoding claims: %w", err)
+	}
+
+	env := map[string]any{
+		"route":   in.Route,
+		"claims":  claims,
+		"request": in.Request,
+	}
+
+	for i, r := range e.rules {
+		if !routeMatches(r.Route, in.Route) || !truthy(r.cond.eval(env)) {
+			continue
+		}
+		if r.Effect == Allow {
+			return auth.Decision{Allowed: true}, nil
+		}
+		return auth.Decision{Reason: fmt.Sprintf("denied by policy rule %d: %s", i, r.Condition)}, nil
+	}
+
+	if e.allow {
+		return auth.Decision{Allowed: true}, nil
+	}
+	return auth.Decision{Reason: "no policy rule allows the request"}, nil
+}
+
+// routeMatches reports whether the route matches the pattern.
+func routeMatches(pattern, route string) bool {
+	if strings.HasSuffix(pattern, "*") {
+		return strings.HasPrefix(route, strings.TrimSuffix(pattern, "*"))
+	}
+	return pattern == route
+}
+
+// toMap converts a value to its JSON object representation.
+func toMap(v any) (map[string]any, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]any
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
@@ -0,0 +1,82 @@
+package policy_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gitamped/seed/auth"
+	"github.com/gitamped/seed/policy"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+const policyDoc = `{
+	"rules": [
+		{"route": "/v1/Orders.*", "effect": "allow", "condition": "'ADMIN' in claims.roles"},
+		{"route": "/v1/Orders.*", "effect": "deny", "condition": "request.owner != claims.sub"},
+		{"route": "/v1/Orders.*", "effect": "allow", "condition": "request.quantity == 1 || (request.bulk == true && !(request.quantity == 0))"}
+	]
+}`
+
+func Test_Engine(t *testing.T) {
+	t.Log("Given the need to authorize by attributes of the caller and request.")
+	{
+		path := filepath.Join(t.TempDir(), "policy.json")
+		if err := os.WriteFile(path, []byte(policyDoc), 0o600); err != nil {
+			t.Fatalf("\t%s\tShould be able to write the policy: %v", failed, err)
+		}
+		e, err := policy.LoadFile(path)
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to load the policy: %v", failed, err)
+		}
+
+		user := auth.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "alice"}, Roles: []string{auth.RoleUser}}
+		admin := auth.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "root"}, Roles: []string{auth.RoleAdmin}}
+
+		ttable := []struct {
+			TestTitle string
+			Route     string
+			Claims    auth.Claims
+			Request   map[string]any
+			Allowed   bool
+		}{
+			{"When acting on their own resource", "/v1/Orders.Create", user, map[string]any{"owner": "alice", "quantity": float64(1)}, true},
+			{"When acting on another subject's resource", "/v1/Orders.Create", user, map[string]any{"owner": "bob", "quantity": float64(1)}, false},
+			{"When the owner is missing", "/v1/Orders.Create", auth.Claims{}, map[string]any{}, false},
+			{"When an admin acts on another subject's resource", "/v1/Orders.Create", admin, map[string]any{"owner": "bob"}, true},
+			{"When ordering in bulk", "/v1/Orders.Create", user, map[string]any{"owner": "alice", "quantity": float64(5), "bulk": true}, true},
+			{"When no rule matches", "/v1/Other.Call", user, nil, false},
+		}
+
+		for testID, td := range ttable {
+			t.Logf("\tTest %d:\t%s.", testID, td.TestTitle)
+			{
+				d, err := e.Authorize(context.Background(), auth.AuthzInput{Route: td.Route, Claims: td.Claims, Request: td.Request})
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to authorize: %v", failed, testID, err)
+				}
+				if d.Allowed != td.Allowed {
+					t.Fatalf("\t%s\tTest %d:\tShould be allowed %v : %+v", failed, testID, td.Allowed, d)
+				}
+				t.Logf("\t%s\tTest %d:\tShould be allowed %v.", success, testID, td.Allowed)
+			}
+		}
+
+		testID := len(ttable)
+		t.Logf("\tTest %d:\tWhen a condition is invalid.", testID)
+		{
+			_, err := policy.New(policy.Policy{Rules: []policy.Rule{{Route: "*", Effect: policy.Allow, Condition: "claims.sub == (1"}}})
+			if err == nil {
+				t.Fatalf("\t%s\tTest %d:\tShould fail to compile.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould fail to compile.", success, testID)
+		}
+	}
+}
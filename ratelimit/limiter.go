@@ -0,0 +1,138 @@
+package ratelimit
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/gitamped/seed/auth"
+)
+
+// KeyFunc identifies the caller a request is limited by. An empty key
+// means the request cannot be identified by this function.
+type KeyFunc func(r *http.Request) string
+
+// schemeAPIKey is the scheme mid.APIKeyAuthenticator records. It is not
+// imported as the mid package depends on this one.
+const schemeAPIKey = "apikey"
+
+// BySubject keys requests by the subject of the authenticated claims.
+func BySubject(r *http.Request) string {
+	claims, err := auth.GetClaims(r.Context())
+	if err != nil || claims.Subject == "" {
+		return ""
+	}
+	return "sub:" + claims.Subject
+}
+
+// ByIP keys requests by the client IP of the connection.
+func ByIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if host == "" {
+		return ""
+	}
+	return "ip:" + host
+}
+
+// ByAPIKey keys requests by the subject of an API key accepted by the
+// API key authenticator, and other requests by client IP. Keys that were
+// not accepted never get a bucket of their own.
+func ByAPIKey(r *http.Request) string {
+	claims, err := auth.GetClaims(r.Context())
+	if err != nil || claims.Subject == "" || auth.GetScheme(r.Context()) != schemeAPIKey {
+		return ByIP(r)
+	}
+	return "key:" + claims.Subject
+}
+
+// FirstKey returns the first non empty key of fns.
+// Example: ratelimit.FirstKey(ratelimit.BySubject, ratelimit.ByIP)
+func FirstKey(fns ...KeyFunc) KeyFunc {
+	return func(r *http.Request) string {
+		for _, fn := range fns {
+			if k := fn(r); k != "" {
+				return k
+			}
+		}
+		return ""
+	}
+}
+
+// Rule applies a Limit to requests for Route made by a caller with Role.
+// An empty Route or Role matches any request.
+type Rule struct {
+	Route string
+	Role  string
+	Limit Limit
+}
+
+// matches reports whether the rule applies to the route and claims.
+func (ru Rule) matches(route string, claims auth.Claims) bool {
+	if ru.Route != "" && ru.Route != route {
+		return false
+	}
+	if ru.Role != "" && !claims.Authorized(ru.Role) {
+		return false
+	}
+	return true
+}
+
+// Limiter applies the first matching rule to each request.
+type Limiter struct {
+	store Store
+	key   KeyFunc
+	rules []Rule
+	now   func() time.Time
+}
+
+// New constructs a Limiter. Rules are matched in order, so more specific
+// rules should be listed first.
+func New(store Store, key KeyFunc, rules ...Rule) (*Limiter, error) {
+	for i, ru := range rules {
+		if err := ru.Limit.Validate(); err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i, err)
+		}
+	}
+
+	l := Limiter{
+		store: store,
+		key:   key,
+		rules: rules,
+		now:   time.Now,
+	}
+
+	return &l, nil
+}
+
+// Take consumes a request from the limit that applies to r. Callers the
+// key function can not identify are limited by client IP. It returns false
+// if no rule applies or the caller has no IP either.
+func (l *Limiter) Take(r *http.Request) (Result, bool, error) {
+	claims, _ := auth.GetClaims(r.Context())
+
+	for i, ru := range l.rules {
+		if !ru.matches(r.URL.Path, claims) {
+			continue
+		}
+
+		key := l.key(r)
+		if key == "" {
+			key = ByIP(r)
+		}
+		if key == "" {
+			return Result{}, false, nil
+		}
+
+		res, err := l.store.Take(fmt.Sprintf("%d|%s|%s", i, ru.Route, key), ru.Limit, l.now())
+		if err != nil {
+			return Result{}, false, fmt.Errorf("taking from store: %w", err)
+		}
+		return res, true, nil
+	}
+
+	return Result{}, false, nil
+}
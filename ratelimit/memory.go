@@ -0,0 +1,84 @@
+package ratelimit
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// MemoryStore is an in-memory Store for a single process.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*entry[Bucket]
+	windows   map[string]*entry[Window]
+	lastSweep time.Time
+}
+
+// entry holds limiter state and when it can be discarded.
+type entry[T any] struct {
+	state   T
+	expires time.Time
+}
+
+// NewMemoryStore constructs an empty MemoryStore ready for use.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: make(map[string]*entry[Bucket]),
+		windows: make(map[string]*entry[Window]),
+	}
+}
+
+// Take implements the Store interface.
+func (ms *MemoryStore) Take(key string, l Limit, now time.Time) (Result, error) {
+	if err := l.Validate(); err != nil {
+		return Result{}, err
+	}
+
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ms.sweep(now)
+
+	switch l.Algorithm {
+	case TokenBucket:
+		e, ok := ms.buckets[key]
+		if !ok {
+			e = &entry[Bucket]{}
+			ms.buckets[key] = e
+		}
+		res := e.state.Take(l, now)
+		e.expires = now.Add(res.Reset)
+		return res, nil
+
+	case SlidingWindow:
+		e, ok := ms.windows[key]
+		if !ok {
+			e = &entry[Window]{}
+			ms.windows[key] = e
+		}
+		res := e.state.Take(l, now)
+		e.expires = now.Add(res.Reset)
+		return res, nil
+	}
+
+	return Result{}, fmt.Errorf("unknown algorithm %d", l.Algorithm)
+}
+
+// sweep removes state that has fully reset, at most once a minute.
+func (ms *MemoryStore) sweep(now time.Time) {
+	if now.Sub(ms.lastSweep) < time.Minute {
+		return
+	}
+	ms.lastSweep = now
+
+	for k, e := range ms.buckets {
+		if now.After(e.expires) {
+			delete(ms.buckets, k)
+		}
+	}
+	for k, e := range ms.windows {
+		if now.After(e.expires) {
+			delete(ms.windows, k)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+// Package ratelimit provides token bucket and sliding window rate limiting
+// with pluggable storage for limiter state.
+package ratelimit
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// Algorithm selects how a Limit is enforced.
+type Algorithm int
+
+// These are the supported rate limiting algorithms.
+const (
+	// TokenBucket refills Requests tokens every Period, up to Burst.
+	TokenBucket Algorithm = iota
+	// SlidingWindow allows Requests per Period, weighting the previous
+	// window by how much of it overlaps the current one.
+	SlidingWindow
+)
+
+// Limit describes how many requests are allowed in a period.
+type Limit struct {
+	Algorithm Algorithm
+	Requests  int
+	Period    time.Duration
+	// Burst is the bucket capacity for TokenBucket. Default: Requests
+	Burst int
+}
+
+// Validate checks the limit is usable.
+func (l Limit) Validate() error {
+	if l.Requests < 1 {
+		return errors.New("limit requests must be positive")
+	}
+	if l.Period <= 0 {
+		return errors.New("limit period must be positive")
+	}
+	if l.Burst < 0 {
+		return errors.New("limit burst must not be negative")
+	}
+	return nil
+}
+
+// capacity returns the maximum number of requests allowed at once.
+func (l Limit) capacity() int {
+	if l.Algorithm == TokenBucket && l.Burst > 0 {
+		return l.Burst
+	}
+	return l.Requests
+}
+
+// Result is the outcome of taking from a limit.
+type Result struct {
+	// Allowed reports whether the request may proceed.
+	Allowed bool
+	// Limit is the number of requests allowed at once.
+	Limit int
+	// Remaining is the number of requests left before being limited.
+	Remaining int
+	// Reset is the time until the limit is fully replenished.
+	Reset time.Duration
+	// RetryAfter is the time until the next request would be allowed.
+	// It is zero when the request was allowed.
+	RetryAfter time.Duration
+}
+
+// Store holds limiter state. Take must atomically consume one request for
+// key under l at time now. Implementations must be safe for concurrent use.
+type Store interface {
+	Take(key string, l Limit, now time.Time) (Result, error)
+}
+
+// =============================================================================
+
+// Bucket is the state of a token bucket.
+type Bucket struct {
+	Tokens float64
+	Last   time.Time
+}
+
+// Take consumes a token from the bucket, refilling it for the time elapsed
+// since it was last used.
+func (b *Bucket) Take(l Limit, now time.Time) Result {
+	capacity := float64(l.capacity())
+	rate := float64(l.Requests) / l.Period.Seconds()
+
+	if b.Last.IsZero() {
+		b.Tokens = capacity
+	} else if elapsed := now.Sub(b.Last).Seconds(); elapsed > 0 {
+		b.Tokens = math.Min(capacity, b.Tokens+elapsed*rate)
+	}
+	b.Last = now
+
+	res := Result{Limit: int(capacity)}
+	if b.Tokens >= 1 {
+		b.Tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = seconds((1 - b.Tokens) / rate)
+	}
+	res.Remaining = int(b.Tokens)
+	res.Reset = seconds((capacity - b.Tokens) / rate)
+
+	return res
+}
+
+// Window is the state of a sliding window counter.
+type Window struct {
+	Start    time.Time
+	Current  int
+	Previous int
+}
+
+// Take counts a request in the window if the weighted count of the current
+// and previous windows is below the limit.
+func (w *Window) Take(l Limit, now time.Time) Result {
+	start := now.Truncate(l.Period)
+	switch {
+	case start.Equal(w.Start):
+	case start.Sub(w.Start) == l.Period:
+		w.Previous, w.Current = w.Current, 0
+	default:
+		w.Previous, w.Current = 0, 0
+	}
+	w.Start = start
+
+	elapsed := now.Sub(start)
+	weight := 1 - float64(elapsed)/float64(l.Period)
+	count := float64(w.Previous)*weight + float64(w.Current)
+
+	res := Result{Limit: l.Requests}
+	if count+1 <= float64(l.Requests) {
+		w.Current++
+		count++
+		res.Allowed = true
+	} else {
+		res.RetryAfter = l.Period - elapsed
+		if w.Previous > 0 && w.Current < l.Requests {
+			// Wait until enough of the previous window has slid out.
+			need := 1 - float64(l.Requests-w.Current-1)/float64(w.Previous)
+			if wait := time.Duration(need*float64(l.Period)) - elapsed; wait < res.RetryAfter {
+				res.RetryAfter = wait
+			}
+		}
+		if res.RetryAfter < time.Second {
+			res.RetryAfter = time.Second
+		}
+	}
+	res.Remaining = l.Requests - int(math.Ceil(count))
+	if res.Remaining < 0 {
+		res.Remaining = 0
+	}
+	res.Reset = l.Period - elapsed
+	if w.Current > 0 {
+		res.Reset += l.Period
+	}
+
+	return res
+}
+
+// seconds converts fractional seconds to a duration.
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
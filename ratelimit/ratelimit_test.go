@@ -0,0 +1,166 @@
+package ratelimit_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gitamped/seed/auth"
+	"github.com/gitamped/seed/mid"
+	"github.com/gitamped/seed/ratelimit"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+func Test_Algorithms(t *testing.T) {
+	t.Log("Given the need to limit the rate of requests.")
+	{
+		now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+		testID := 0
+		t.Logf("\tTest %d:\tWhen using a token bucket.", testID)
+		{
+			ms := ratelimit.NewMemoryStore()
+			l := ratelimit.Limit{Algorithm: ratelimit.TokenBucket, Requests: 2, Period: time.Second}
+
+			for i := 0; i < 2; i++ {
+				if res, _ := ms.Take("k", l, now); !res.Allowed {
+					t.Fatalf("\t%s\tTest %d:\tShould allow request %d.", failed, testID, i)
+				}
+			}
+			res, _ := ms.Take("k", l, now)
+			if res.Allowed || res.RetryAfter != 500*time.Millisecond {
+				t.Fatalf("\t%s\tTest %d:\tShould deny the third request with retry after 500ms : %+v", failed, testID, res)
+			}
+			t.Logf("\t%s\tTest %d:\tShould deny requests over the burst.", success, testID)
+
+			if res, _ := ms.Take("k", l, now.Add(500*time.Millisecond)); !res.Allowed {
+				t.Fatalf("\t%s\tTest %d:\tShould allow a request once refilled : %+v", failed, testID, res)
+			}
+			t.Logf("\t%s\tTest %d:\tShould allow a request once refilled.", success, testID)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tWhen using a sliding window.", testID)
+		{
+			ms := ratelimit.NewMemoryStore()
+			l := ratelimit.Limit{Algorithm: ratelimit.SlidingWindow, Requests: 2, Period: time.Minute}
+
+			for i := 0; i < 2; i++ {
+				if res, _ := ms.Take("k", l, now); !res.Allowed {
+					t.Fatalf("\t%s\tTest %d:\tShould allow request %d.", failed, testID, i)
+				}
+			}
+			if res, _ := ms.Take("k", l, now.Add(30*time.Second)); res.Allowed {
+				t.Fatalf("\t%s\tTest %d:\tShould deny requests over the limit : %+v", failed, testID, res)
+			}
+			t.Logf("\t%s\tTest %d:\tShould deny requests over the limit.", success, testID)
+
+			// Halfway through the next window, half the previous count remains.
+			if res, _ := ms.Take("k", l, now.Add(90*time.Second)); !res.Allowed {
+				t.Fatalf("\t%s\tTest %d:\tShould allow a request as the window slides : %+v", failed, testID, res)
+			}
+			if res, _ := ms.Take("k", l, now.Add(90*time.Second)); res.Allowed {
+				t.Fatalf("\t%s\tTest %d:\tShould weight the previous window : %+v", failed, testID, res)
+			}
+			t.Logf("\t%s\tTest %d:\tShould weight the previous window.", success, testID)
+		}
+	}
+}
+
+func Test_Middleware(t *testing.T) {
+	t.Log("Given the need to limit requests per route.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen a client exceeds the route limit.", testID)
+		{
+			l, err := ratelimit.New(ratelimit.NewMemoryStore(), ratelimit.ByIP,
+				ratelimit.Rule{Route: "/v1/Expensive.Call", Limit: ratelimit.Limit{Requests: 1, Period: time.Minute}},
+			)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create a limiter: %v", failed, testID, err)
+			}
+			h := mid.RateLimitMiddleware(l)(func(w http.ResponseWriter, r *http.Request) {})
+
+			codes := make([]int, 0, 3)
+			for _, path := range []string{"/v1/Expensive.Call", "/v1/Expensive.Call", "/v1/Cheap.Call"} {
+				w := httptest.NewRecorder()
+				h(w, httptest.NewRequest(http.MethodPost, path, nil))
+				codes = append(codes, w.Code)
+
+				if w.Code == http.StatusTooManyRequests && w.Header().Get("Retry-After") != "60" {
+					t.Fatalf("\t%s\tTest %d:\tShould set Retry-After : %q", failed, testID, w.Header().Get("Retry-After"))
+				}
+			}
+
+			if codes[0] != http.StatusOK || codes[1] != http.StatusTooManyRequests || codes[2] != http.StatusOK {
+				t.Fatalf("\t%s\tTest %d:\tShould only limit the configured route : %v", failed, testID, codes)
+			}
+			t.Logf("\t%s\tTest %d:\tShould only limit the configured route.", success, testID)
+		}
+	}
+}
+
+func Test_Keys(t *testing.T) {
+	t.Log("Given the need to limit every caller, identified or not.")
+	{
+		type caller struct {
+			Subject string
+			Scheme  string
+			IP      string
+		}
+
+		request := func(testID int, c caller) *http.Request {
+			r := httptest.NewRequest(http.MethodPost, "/v1/Expensive.Call", nil)
+			r.RemoteAddr = c.IP + ":1234"
+			r.Header.Set("X-API-Key", fmt.Sprintf("random-%d-%s", testID, c.IP))
+			if c.Subject != "" {
+				ctx := auth.SetClaims(r.Context(), auth.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: c.Subject}})
+				r = r.WithContext(auth.SetScheme(ctx, c.Scheme))
+			}
+			return r
+		}
+
+		ttable := []struct {
+			TestTitle string
+			Key       ratelimit.KeyFunc
+			First     caller
+			Second    caller
+			Limited   bool
+		}{
+			{"When anonymous callers hit a subject rule", ratelimit.BySubject, caller{IP: "10.0.0.1"}, caller{IP: "10.0.0.1"}, true},
+			{"When unaccepted api keys vary", ratelimit.ByAPIKey, caller{IP: "10.0.0.1"}, caller{IP: "10.0.0.1"}, true},
+			{"When an accepted api key moves ip", ratelimit.ByAPIKey, caller{"partner", "apikey", "10.0.0.1"}, caller{"partner", "apikey", "10.0.0.2"}, true},
+			{"When two api keys share an ip", ratelimit.ByAPIKey, caller{"partner", "apikey", "10.0.0.1"}, caller{"other", "apikey", "10.0.0.1"}, false},
+			{"When a bearer token shares an ip with an api key", ratelimit.ByAPIKey, caller{"partner", "apikey", "10.0.0.1"}, caller{"partner", "bearer", "10.0.0.1"}, false},
+		}
+
+		for testID, td := range ttable {
+			t.Logf("\tTest %d:\t%s.", testID, td.TestTitle)
+			{
+				l, err := ratelimit.New(ratelimit.NewMemoryStore(), td.Key,
+					ratelimit.Rule{Limit: ratelimit.Limit{Requests: 1, Period: time.Minute}},
+				)
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to create a limiter: %v", failed, testID, err)
+				}
+
+				if res, ok, err := l.Take(request(testID, td.First)); err != nil || !ok || !res.Allowed {
+					t.Fatalf("\t%s\tTest %d:\tShould allow the first request : %v %v %+v", failed, testID, err, ok, res)
+				}
+				res, ok, err := l.Take(request(testID, td.Second))
+				if err != nil || !ok || res.Allowed == td.Limited {
+					t.Fatalf("\t%s\tTest %d:\tShould limit the second request %v : %v %v %+v", failed, testID, td.Limited, err, ok, res)
+				}
+				t.Logf("\t%s\tTest %d:\tShould limit the second request %v.", success, testID, td.Limited)
+			}
+		}
+	}
+}
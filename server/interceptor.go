@@ -0,0 +1,37 @@
+package server
+
+// UnaryHandler invokes the rpc procedure for a request, or the next
+// interceptor in the chain.
+type UnaryHandler func(g GenericRequest, b []byte) (any, error)
+
+// Interceptor wraps the invocation of an rpc procedure. It receives the
+// route being called, the request and its raw body, and must call next to
+// continue the chain. The returned response and error may be inspected or
+// replaced before they are validated and encoded.
+type Interceptor func(route string, g GenericRequest, b []byte, next UnaryHandler) (any, error)
+
+// Intercept appends interceptors to the server. Interceptors run in the
+// order they are added, the first being the outermost.
+func (s *Server) Intercept(i ...Interceptor) {
+	s.Interceptors = append(s.Interceptors, i...)
+}
+
+// ChainInterceptors wraps h with the interceptors for the given route.
+func ChainInterceptors(route string, h UnaryHandler, i ...Interceptor) UnaryHandler {
+
+	if len(i) < 1 {
+		return h
+	}
+
+	wrapped := h
+
+	// loop in reverse to preserve interceptor order
+	for n := len(i) - 1; n >= 0; n-- {
+		interceptor, next := i[n], wrapped
+		wrapped = func(g GenericRequest, b []byte) (any, error) {
+			return interceptor(route, g, b, next)
+		}
+	}
+
+	return wrapped
+}
@@ -30,7 +30,17 @@ import (
 )
 
 type RPCEndpoint struct {
-	Roles   []string
+	Roles []string
+	// Permissions required to call the endpoint, as granted to roles by
+	// Server.Permissions.
+	Permissions auth.Requirement
+	// TenantScoped requires the tenant of the request body to match the
+	// tenant of the caller. Roles the caller only has within its tenant
+	// apply to tenant scoped endpoints only.
+	TenantScoped bool
+	// StepUp requires the caller to have authenticated with a second
+	// factor.
+	StepUp  bool
 	Handler func(GenericRequest, []byte) (any, error)
 }
 
@@ -47,6 +57,28 @@ type GenericRequest struct {
 	Values *values.Values
 }
 
+// Tenant returns the tenant the caller acts in.
+func (g GenericRequest) Tenant() string {
+	return g.Claims.Tenant
+}
+
+// Subject returns who the request is made for.
+func (g GenericRequest) Subject() string {
+	return g.Claims.Subject
+}
+
+// Actor returns who made the request. It differs from Subject when the
+// actor is impersonating the subject.
+func (g GenericRequest) Actor() string {
+	return g.Claims.Actor()
+}
+
+// ClaimsAs returns the application claims of the caller, for claims types
+// that embed auth.Claims.
+func ClaimsAs[T auth.Extension](g GenericRequest) (T, error) {
+	return auth.ClaimsAs[T](g.Claims)
+}
+
 // Register adds a handler for the specified service method.
 func (s *Server) Register(service, path string, r RPCEndpoint) {
 	s.Routes[fmt.Sprintf("%s%s.%s", s.Basepath, service, path)] = r
@@ -54,8 +86,9 @@ func (s *Server) Register(service, path string, r RPCEndpoint) {
 
 func NewServer(mw []mid.Middleware) *Server {
 	s := &Server{
-		Basepath: "/v1/",
-		Routes:   make(map[string]RPCEndpoint),
+		Basepath:    "/v1/",
+		Routes:      make(map[string]RPCEndpoint),
+		TenantField: "tenant",
 		OnErr: func(w http.ResponseWriter, r *http.Request, err error) {
 			errObj := struct {
 				Error string `json:"error"`
@@ -87,10 +120,28 @@ type Server struct {
 	NotFound http.Handler
 	// OnErr is called when there is an error.
 	OnErr func(w http.ResponseWriter, r *http.Request, err error)
+	// Interceptors wrap every rpc procedure call, in order.
+	Interceptors []Interceptor
+	// CORS configures cross-origin requests from browsers. Preflight
+	// requests are answered before any other handling. Optional.
+	CORS *mid.CORS
+	// Permissions maps roles to permissions and the roles they imply.
+	// Optional.
+	Permissions *auth.Permissions
+	// Authorizer is called for every rpc once roles and permissions have
+	// been checked. Optional.
+	Authorizer auth.Authorizer
+	// TenantField is the request body field holding the tenant for tenant
+	// scoped endpoints.
+	// Default: tenant
+	TenantField string
 }
 
 // ServeHTTP serves the request.
 func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.CORS != nil && s.CORS.Handle(w, r) {
+		return
+	}
 	if r.Method != http.MethodPost {
 		s.NotFound.ServeHTTP(w, r)
 		return
@@ -142,18 +193,80 @@ func Authorized(rpcRoles, userRoles []string) bool {
 	return false
 }
 
+// Unauthorized responds with 401 asking the client to authenticate.
 func Unauthorized(w http.ResponseWriter, r *http.Request) {
+	challenge(w, "", "")
 	http.Error(w, "401 Unauthorized", http.StatusUnauthorized)
 }
 
+// InvalidToken responds with 401 describing why the token was rejected.
+func InvalidToken(w http.ResponseWriter, r *http.Request, description string) {
+	challenge(w, "invalid_token", description)
+	http.Error(w, "401 Unauthorized", http.StatusUnauthorized)
+}
+
+// Forbidden responds with 403 for an authenticated client that is not
+// allowed to call the endpoint.
+func Forbidden(w http.ResponseWriter, r *http.Request, description string) {
+	challenge(w, "insufficient_scope", description)
+	http.Error(w, "403 Forbidden", http.StatusForbidden)
+}
+
+// StepUpRequired responds with 401 asking the client to authenticate with
+// a second factor, as described in RFC 9470.
+func StepUpRequired(w http.ResponseWriter, r *http.Request) {
+	challenge(w, "insufficient_user_authentication", "second factor required")
+	http.Error(w, "401 Unauthorized", http.StatusUnauthorized)
+}
+
+// challenge sets the WWW-Authenticate header as described in RFC 6750.
+func challenge(w http.ResponseWriter, code, description string) {
+	v := `Bearer realm="seed"`
+	if code != "" {
+		v += fmt.Sprintf(`, error="%s"`, code)
+	}
+	if description != "" {
+		esc := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+		v += fmt.Sprintf(`, error_description="%s"`, esc.Replace(description))
+	}
+	w.Header().Set("WWW-Authenticate", v)
+}
+
+// describeAuthError returns a description of why a token was rejected
+// that is safe to send to the client.
+func describeAuthError(err error) string {
+	for _, known := range []error{
+		auth.ErrTokenExpired,
+		auth.ErrTokenNotValidYet,
+		auth.ErrTokenTooOld,
+		auth.ErrTokenRevoked,
+		auth.ErrTokenInvalidIssuer,
+		auth.ErrTokenInvalidAudience,
+		auth.ErrTokenMissingClaim,
+		auth.ErrTokenMalformed,
+		auth.ErrTokenSignatureInvalid,
+	} {
+		if errors.Is(err, known) {
+			return known.Error()
+		}
+	}
+	return "token is invalid"
+}
+
+// StatusBadRequest responds with 400 for request bodies that can not be
+// processed.
+func StatusBadRequest(w http.ResponseWriter, r *http.Request) {
+	http.Error(w, "400 Bad Request", http.StatusBadRequest)
+}
+
 func StatusNotAcceptable(w http.ResponseWriter, r *http.Request) {
 	http.Error(w, "406 Not Acceptable", http.StatusNotAcceptable)
 }
 
 func (s *Server) DefaultHandler(w http.ResponseWriter, r *http.Request) {
-	g := GenericRequest{}
-
 	ctx := r.Context()
+	g := GenericRequest{Ctx: ctx}
+
 	rpc, ok := s.Routes[r.URL.Path]
 
 	if !ok {
@@ -161,21 +274,22 @@ func (s *Server) DefaultHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if len(rpc.Roles) > 0 {
-		claims, err := auth.GetClaims(ctx)
-		if err != nil {
-			Unauthorized(w, r)
+	// Claims are set for public endpoints too, so they can be authorized.
+	claims, err := auth.GetClaims(ctx)
+	if err == nil {
+		values.SetIdentity(ctx, claims.Actor(), claims.Subject)
+	}
+	if err != nil && (len(rpc.Roles) > 0 || !rpc.Permissions.IsZero() || rpc.TenantScoped || rpc.StepUp) {
+		if authErr := auth.GetAuthError(ctx); authErr != nil {
+			values.SetDenyReason(ctx, fmt.Sprintf("invalid credentials: %s", authErr))
+			InvalidToken(w, r, describeAuthError(authErr))
 			return
 		}
-		g.Claims = claims
-	} else {
-		g.Claims = auth.Claims{}
-	}
-
-	if ok := Authorized(rpc.Roles, g.Claims.Roles); !ok {
+		values.SetDenyReason(ctx, "missing credentials")
 		Unauthorized(w, r)
 		return
 	}
+	g.Claims = claims
 
 	v, err := values.GetValues(ctx)
 	if err != nil {
@@ -190,7 +304,66 @@ func (s *Server) DefaultHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response, err := rpc.Handler(g, b)
+	var req map[string]any
+	if rpc.TenantScoped || s.Authorizer != nil {
+		req, err = auth.DecodeRequest(b)
+		if errors.Is(err, auth.ErrAmbiguousRequest) {
+			values.SetDenyReason(ctx, err.Error())
+			StatusBadRequest(w, r)
+			return
+		}
+	}
+
+	// Tenant roles only apply to tenant scoped endpoints, once the request
+	// is known to be for the tenant of the caller.
+	roles := g.Claims.Roles
+	if rpc.TenantScoped {
+		field, _ := auth.Field(req, s.TenantField)
+		tenant, _ := field.(string)
+		if g.Claims.Tenant == "" || tenant != g.Claims.Tenant {
+			values.SetDenyReason(ctx, fmt.Sprintf("tenant %q does not match caller tenant %q", tenant, g.Claims.Tenant))
+			Forbidden(w, r, "tenant mismatch")
+			return
+		}
+		roles = g.Claims.RolesFor(tenant)
+	}
+	roles = s.Permissions.ExpandRoles(roles)
+
+	if ok := Authorized(rpc.Roles, roles); !ok {
+		reason := fmt.Sprintf("missing any of roles: %s", strings.Join(rpc.Roles, ", "))
+		values.SetDenyReason(ctx, reason)
+		Forbidden(w, r, "insufficient role")
+		return
+	}
+
+	if d := s.Permissions.Check(roles, rpc.Permissions); !d.Allowed {
+		values.SetDenyReason(ctx, d.Reason)
+		Forbidden(w, r, "insufficient permissions")
+		return
+	}
+
+	if rpc.StepUp && !g.Claims.SteppedUp() {
+		values.SetDenyReason(ctx, "second factor required")
+		StepUpRequired(w, r)
+		return
+	}
+
+	if s.Authorizer != nil {
+		in := auth.AuthzInput{Route: r.URL.Path, Claims: g.Claims, Request: req}
+		d, err := s.Authorizer.Authorize(ctx, in)
+		if err != nil {
+			s.OnErr(w, r, err)
+			return
+		}
+		if !d.Allowed {
+			values.SetDenyReason(ctx, d.Reason)
+			Forbidden(w, r, "")
+			return
+		}
+	}
+
+	handler := ChainInterceptors(r.URL.Path, rpc.Handler, s.Interceptors...)
+	response, err := handler(g, b)
 
 	if err != nil {
 		s.OnErr(w, r, err)
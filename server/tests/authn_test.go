@@ -0,0 +1,83 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gitamped/seed/auth"
+	"github.com/gitamped/seed/mid"
+	"github.com/gitamped/seed/server"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func Test_AuthenticatorChain(t *testing.T) {
+	t.Log("Given the need to accept several authentication schemes")
+	{
+		a := GetAuth()
+		chain := mid.ChainAuthMiddleware(mid.BearerAuthenticator(a), mid.BasicAuthenticator(credentials{}))
+		s := server.NewServer(append([]mid.Middleware{chain}, mid.CommonMiddleware...))
+		s.Register("SchemeService", "Get", server.RPCEndpoint{
+			Roles: []string{auth.RoleUser},
+			Handler: func(g server.GenericRequest, b []byte) (any, error) {
+				return struct{ Scheme string }{auth.GetScheme(g.Ctx)}, nil
+			},
+		})
+
+		userToken, _ := a.GenerateToken(auth.Claims{Roles: []string{auth.RoleUser}})
+
+		ttable := []struct {
+			TestTitle          string
+			Prepare            func(r *http.Request)
+			ExpectedStatusCode int
+			ExpectedScheme     string
+		}{
+			{"When passed a bearer token", func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+userToken) }, http.StatusOK, mid.SchemeBearer},
+			{"When passed basic credentials", func(r *http.Request) { r.SetBasicAuth("seed", "secret") }, http.StatusOK, mid.SchemeBasic},
+			{"When passed invalid basic credentials", func(r *http.Request) { r.SetBasicAuth("seed", "wrong") }, http.StatusUnauthorized, ""},
+		}
+
+		for testID, td := range ttable {
+			t.Logf("\tTest %d:\t%s", testID, td.TestTitle)
+			{
+				r := httptest.NewRequest(http.MethodPost, "/v1/SchemeService.Get", bytes.NewBufferString(`{}`))
+				td.Prepare(r)
+				w := httptest.NewRecorder()
+				s.ServeHTTP(w, r)
+
+				if w.Code != td.ExpectedStatusCode {
+					t.Fatalf("\t%s\tTest %d:\tShould receive a status code of %d for the response : %v", Failed, testID, td.ExpectedStatusCode, w.Code)
+				}
+				t.Logf("\t%s\tTest %d:\tShould receive a status code of %d for the response.", Success, testID, td.ExpectedStatusCode)
+
+				if w.Code != http.StatusOK {
+					continue
+				}
+
+				var got struct{ Scheme string }
+				if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to unmarshal the response : %v", Failed, testID, err)
+				}
+				if got.Scheme != td.ExpectedScheme {
+					t.Fatalf("\t%s\tTest %d:\tShould record the %s scheme : %s", Failed, testID, td.ExpectedScheme, got.Scheme)
+				}
+				t.Logf("\t%s\tTest %d:\tShould record the %s scheme.", Success, testID, td.ExpectedScheme)
+			}
+		}
+	}
+}
+
+// =============================================================================
+
+type credentials struct{}
+
+func (credentials) Verify(ctx context.Context, username, password string) (auth.Claims, error) {
+	if username != "seed" || password != "secret" {
+		return auth.Claims{}, errors.New("invalid credentials")
+	}
+	return auth.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: username}, Roles: []string{auth.RoleUser}}, nil
+}
@@ -0,0 +1,83 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gitamped/seed/auth"
+	"github.com/gitamped/seed/mid"
+	"github.com/gitamped/seed/policy"
+	"github.com/gitamped/seed/server"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func Test_Authorizer(t *testing.T) {
+	t.Log("Given the need to authorize requests by their attributes")
+	{
+		engine, err := policy.New(policy.Policy{
+			Rules: []policy.Rule{
+				{Route: "/v1/DocService.Update", Effect: policy.Allow, Condition: "request.owner == claims.sub"},
+			},
+		})
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to create a policy engine : %v", Failed, err)
+		}
+
+		a := GetAuth()
+		mw := append([]mid.Middleware{mid.AuthMiddleware(a)}, mid.CommonMiddleware...)
+		s := server.NewServer(mw)
+		s.Authorizer = engine
+		s.Register("DocService", "Update", server.RPCEndpoint{
+			Roles: []string{auth.RoleUser},
+			Handler: func(g server.GenericRequest, b []byte) (any, error) {
+				var req struct {
+					Owner string `json:"owner"`
+				}
+				if err := json.Unmarshal(b, &req); err != nil {
+					return nil, err
+				}
+				return struct{ Owner string }{req.Owner}, nil
+			},
+		})
+
+		token, _ := a.GenerateToken(auth.Claims{
+			RegisteredClaims: jwt.RegisteredClaims{Subject: "alice"},
+			Roles:            []string{auth.RoleUser},
+		})
+
+		ttable := []struct {
+			TestTitle          string
+			RequestData        string
+			ExpectedStatusCode int
+		}{
+			{"When the caller owns the document", `{"owner": "alice"}`, http.StatusOK},
+			{"When the caller owns the document in another case", `{"Owner": "alice"}`, http.StatusOK},
+			{"When another user owns the document", `{"owner": "bob"}`, http.StatusForbidden},
+			{"When members only differ in case", `{"owner": "alice", "Owner": "bob"}`, http.StatusBadRequest},
+			{"When nested members only differ in case", `{"owner": "alice", "doc": {"id": 1, "ID": 2}}`, http.StatusBadRequest},
+		}
+
+		for testID, td := range ttable {
+			t.Logf("\tTest %d:\t%s", testID, td.TestTitle)
+			{
+				r := httptest.NewRequest(http.MethodPost, "/v1/DocService.Update", bytes.NewBufferString(td.RequestData))
+				r.Header.Set("Authorization", "Bearer "+token)
+				w := httptest.NewRecorder()
+				s.ServeHTTP(w, r)
+
+				if w.Code != td.ExpectedStatusCode {
+					t.Fatalf("\t%s\tTest %d:\tShould receive a status code of %d for the response : %v", Failed, testID, td.ExpectedStatusCode, w.Code)
+				}
+				t.Logf("\t%s\tTest %d:\tShould receive a status code of %d for the response.", Success, testID, td.ExpectedStatusCode)
+
+				var resp struct{ Owner string }
+				if w.Code == http.StatusOK && (json.Unmarshal(w.Body.Bytes(), &resp) != nil || resp.Owner != "alice") {
+					t.Fatalf("\t%s\tTest %d:\tShould act on the authorized owner : %s", Failed, testID, w.Body.String())
+				}
+			}
+		}
+	}
+}
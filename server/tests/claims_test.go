@@ -0,0 +1,84 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gitamped/seed/auth"
+	"github.com/gitamped/seed/mid"
+	"github.com/gitamped/seed/server"
+)
+
+// appClaims are application claims extending the base claims.
+type appClaims struct {
+	auth.Claims
+	Email    string          `json:"email"`
+	Features map[string]bool `json:"features"`
+}
+
+func Test_ExtendedClaims(t *testing.T) {
+	t.Log("Given the need to carry application claims in tokens")
+	{
+		a := GetAuth()
+		mw := append([]mid.Middleware{mid.AuthMiddleware(a)}, mid.CommonMiddleware...)
+		s := server.NewServer(mw)
+		s.Register("ProfileService", "Get", server.RPCEndpoint{
+			Roles: []string{auth.RoleUser},
+			Handler: func(g server.GenericRequest, b []byte) (any, error) {
+				c, err := server.ClaimsAs[appClaims](g)
+				if err != nil {
+					return nil, err
+				}
+				return struct {
+					Email string
+					Beta  bool
+				}{c.Email, c.Features["beta"]}, nil
+			},
+		})
+
+		token, err := auth.GenerateExtendedToken(a, appClaims{
+			Claims:   auth.Claims{Roles: []string{auth.RoleUser}},
+			Email:    "user@example.com",
+			Features: map[string]bool{"beta": true},
+		})
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to generate a token : %v", Failed, err)
+		}
+		t.Logf("\t%s\tShould be able to generate a token.", Success)
+
+		t.Logf("\tTest %d:\t%s", 0, "When validating the token")
+		{
+			c, err := auth.ValidateExtendedToken[appClaims](a, token)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to validate the token : %v", Failed, 0, err)
+			}
+			if c.Email != "user@example.com" || c.ID == "" || !c.Authorized(auth.RoleUser) {
+				t.Fatalf("\t%s\tTest %d:\tShould recover the application claims : %+v", Failed, 0, c)
+			}
+			t.Logf("\t%s\tTest %d:\tShould recover the application claims.", Success, 0)
+		}
+
+		t.Logf("\tTest %d:\t%s", 1, "When calling an rpc")
+		{
+			r := httptest.NewRequest(http.MethodPost, "/v1/ProfileService.Get", bytes.NewBufferString(`{}`))
+			r.Header.Set("Authorization", "Bearer "+token)
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, r)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("\t%s\tTest %d:\tShould receive a status code of %d for the response : %v", Failed, 1, http.StatusOK, w.Code)
+			}
+			var resp struct {
+				Email string
+				Beta  bool
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Email != "user@example.com" || !resp.Beta {
+				t.Fatalf("\t%s\tTest %d:\tShould read the application claims in the handler : %s", Failed, 1, w.Body.String())
+			}
+			t.Logf("\t%s\tTest %d:\tShould read the application claims in the handler.", Success, 1)
+		}
+	}
+}
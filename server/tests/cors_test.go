@@ -0,0 +1,114 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gitamped/seed/mid"
+	"github.com/gitamped/seed/server"
+)
+
+func Test_CORS(t *testing.T) {
+	t.Log("Given the need to serve browser clients on other origins")
+	{
+		s := server.NewServer(mid.CommonMiddleware)
+		s.CORS = &mid.CORS{
+			AllowedOrigins:   []string{"https://*.example.com"},
+			AllowCredentials: true,
+			MaxAge:           600,
+		}
+		NewSecretGreeterServicer().Register(s)
+
+		ttable := []struct {
+			TestTitle          string
+			Origin             string
+			RequestHeaders     string
+			ExpectedStatusCode int
+			ExpectedOrigin     string
+		}{
+			{
+				TestTitle:          "When preflighting from an allowed subdomain",
+				Origin:             "https://app.example.com",
+				RequestHeaders:     "authorization, content-type",
+				ExpectedStatusCode: http.StatusNoContent,
+				ExpectedOrigin:     "https://app.example.com",
+			},
+			{
+				TestTitle:          "When preflighting from another origin",
+				Origin:             "https://example.com.evil.io",
+				ExpectedStatusCode: http.StatusForbidden,
+			},
+			{
+				TestTitle:          "When preflighting with a header that is not allowed",
+				Origin:             "https://app.example.com",
+				RequestHeaders:     "x-custom",
+				ExpectedStatusCode: http.StatusForbidden,
+			},
+		}
+
+		for testID, td := range ttable {
+			t.Logf("\tTest %d:\t%s", testID, td.TestTitle)
+			{
+				r := httptest.NewRequest(http.MethodOptions, "/v1/GreeterService.SecretGreet", nil)
+				r.Header.Set("Origin", td.Origin)
+				r.Header.Set("Access-Control-Request-Method", http.MethodPost)
+				if td.RequestHeaders != "" {
+					r.Header.Set("Access-Control-Request-Headers", td.RequestHeaders)
+				}
+				w := httptest.NewRecorder()
+				s.ServeHTTP(w, r)
+
+				if w.Code != td.ExpectedStatusCode {
+					t.Fatalf("\t%s\tTest %d:\tShould receive a status code of %d for the response : %v", Failed, testID, td.ExpectedStatusCode, w.Code)
+				}
+				t.Logf("\t%s\tTest %d:\tShould receive a status code of %d for the response.", Success, testID, td.ExpectedStatusCode)
+
+				if got := w.Header().Get("Access-Control-Allow-Origin"); got != td.ExpectedOrigin {
+					t.Fatalf("\t%s\tTest %d:\tShould allow origin %q : %q", Failed, testID, td.ExpectedOrigin, got)
+				}
+				t.Logf("\t%s\tTest %d:\tShould allow origin %q.", Success, testID, td.ExpectedOrigin)
+			}
+		}
+	}
+}
+
+func Test_CORSSimple(t *testing.T) {
+	t.Log("Given the need to allow credentials only to trusted origins")
+	{
+		ttable := []struct {
+			TestTitle           string
+			AllowedOrigins      []string
+			Origin              string
+			ExpectedOrigin      string
+			ExpectedCredentials string
+		}{
+			{"When an allowed subdomain calls", []string{"https://*.example.com"}, "https://app.example.com", "https://app.example.com", "true"},
+			{"When another origin calls", []string{"https://*.example.com"}, "https://evil.io", "", ""},
+			{"When any origin is allowed", []string{"*"}, "https://evil.io", "*", ""},
+			{"When an origin is also listed explicitly", []string{"*", "https://app.example.com"}, "https://app.example.com", "https://app.example.com", "true"},
+		}
+
+		for testID, td := range ttable {
+			t.Logf("\tTest %d:\t%s", testID, td.TestTitle)
+			{
+				s := server.NewServer(mid.CommonMiddleware)
+				s.CORS = &mid.CORS{AllowedOrigins: td.AllowedOrigins, AllowCredentials: true}
+				NewSecretGreeterServicer().Register(s)
+
+				r := httptest.NewRequest(http.MethodPost, "/v1/GreeterService.SecretGreet", nil)
+				r.Header.Set("Origin", td.Origin)
+				w := httptest.NewRecorder()
+				s.ServeHTTP(w, r)
+
+				if got := w.Header().Get("Access-Control-Allow-Origin"); got != td.ExpectedOrigin {
+					t.Fatalf("\t%s\tTest %d:\tShould allow origin %q : %q", Failed, testID, td.ExpectedOrigin, got)
+				}
+				if got := w.Header().Get("Access-Control-Allow-Credentials"); got != td.ExpectedCredentials {
+					t.Fatalf("\t%s\tTest %d:\tShould allow credentials %q : %q", Failed, testID, td.ExpectedCredentials, got)
+				}
+				t.Logf("\t%s\tTest %d:\tShould allow origin %q with credentials %q.", Success, testID, td.ExpectedOrigin, td.ExpectedCredentials)
+			}
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gitamped/seed/auth"
+	"github.com/gitamped/seed/mid"
+	"github.com/gitamped/seed/server"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func Test_Impersonation(t *testing.T) {
+	t.Log("Given the need to know who acts on behalf of whom")
+	{
+		a := GetAuth()
+		mw := append([]mid.Middleware{mid.AuthMiddleware(a)}, mid.CommonMiddleware...)
+		s := server.NewServer(mw)
+		s.Register("WhoamiService", "Get", server.RPCEndpoint{
+			Roles: []string{auth.RoleUser},
+			Handler: func(g server.GenericRequest, b []byte) (any, error) {
+				return struct{ Actor, Subject string }{g.Actor(), g.Subject()}, nil
+			},
+		})
+
+		support := auth.Claims{
+			RegisteredClaims: jwt.RegisteredClaims{Subject: "support"},
+			Roles:            []string{auth.RoleImpersonator},
+		}
+		user := auth.Claims{
+			RegisteredClaims: jwt.RegisteredClaims{Subject: "user"},
+			Roles:            []string{auth.RoleUser},
+		}
+		userToken, _ := a.GenerateToken(user)
+		impersonationToken, err := a.GenerateImpersonationToken(support, user)
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to generate an impersonation token : %v", Failed, err)
+		}
+
+		ttable := []struct {
+			TestTitle       string
+			Token           string
+			ExpectedActor   string
+			ExpectedSubject string
+		}{
+			{"When the user calls", userToken, "user", "user"},
+			{"When support calls as the user", impersonationToken, "support", "user"},
+		}
+
+		for testID, td := range ttable {
+			t.Logf("\tTest %d:\t%s", testID, td.TestTitle)
+			{
+				r := httptest.NewRequest(http.MethodPost, "/v1/WhoamiService.Get", bytes.NewBufferString(`{}`))
+				r.Header.Set("Authorization", "Bearer "+td.Token)
+				w := httptest.NewRecorder()
+				s.ServeHTTP(w, r)
+
+				var resp struct{ Actor, Subject string }
+				if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould receive a response : %v", Failed, testID, w.Body.String())
+				}
+				if resp.Actor != td.ExpectedActor || resp.Subject != td.ExpectedSubject {
+					t.Fatalf("\t%s\tTest %d:\tShould see actor %q for subject %q : %+v", Failed, testID, td.ExpectedActor, td.ExpectedSubject, resp)
+				}
+				t.Logf("\t%s\tTest %d:\tShould see actor %q for subject %q.", Success, testID, td.ExpectedActor, td.ExpectedSubject)
+			}
+		}
+	}
+}
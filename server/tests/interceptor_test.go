@@ -0,0 +1,70 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gitamped/seed/mid"
+	"github.com/gitamped/seed/server"
+)
+
+func Test_Interceptors(t *testing.T) {
+	t.Log("Given the need to intercept rpc procedure calls")
+	{
+		s := server.NewServer(mid.CommonMiddleware)
+		NewSecretGreeterServicer().Register(s)
+		s.Register("GreeterService", "Fail", server.RPCEndpoint{
+			Handler: func(g server.GenericRequest, b []byte) (any, error) {
+				return nil, errors.New("internal failure")
+			},
+		})
+
+		var order []string
+		s.Intercept(
+			func(route string, g server.GenericRequest, b []byte, next server.UnaryHandler) (any, error) {
+				order = append(order, "outer:"+route)
+				return next(g, b)
+			},
+			func(route string, g server.GenericRequest, b []byte, next server.UnaryHandler) (any, error) {
+				order = append(order, "inner:"+route)
+				resp, err := next(g, b)
+				if err != nil {
+					return SecretGreetResponse{Error: "translated: " + err.Error()}, nil
+				}
+				return resp, nil
+			},
+		)
+
+		testID := 0
+		t.Logf("\tTest %d:\tWhen a handler returns an error.", testID)
+		{
+			r := httptest.NewRequest(http.MethodPost, "/v1/GreeterService.Fail", bytes.NewBufferString(`{}`))
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, r)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("\t%s\tTest %d:\tShould receive a status code of 200 for the response : %v", Failed, testID, w.Code)
+			}
+			t.Logf("\t%s\tTest %d:\tShould receive a status code of 200 for the response.", Success, testID)
+
+			var got SecretGreetResponse
+			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to unmarshal the response : %v", Failed, testID, err)
+			}
+			if got.Error != "translated: internal failure" {
+				t.Fatalf("\t%s\tTest %d:\tShould return the translated error : %s", Failed, testID, got.Error)
+			}
+			t.Logf("\t%s\tTest %d:\tShould return the translated error.", Success, testID)
+
+			exp := []string{"outer:/v1/GreeterService.Fail", "inner:/v1/GreeterService.Fail"}
+			if len(order) != len(exp) || order[0] != exp[0] || order[1] != exp[1] {
+				t.Fatalf("\t%s\tTest %d:\tShould run interceptors in order : %v", Failed, testID, order)
+			}
+			t.Logf("\t%s\tTest %d:\tShould run interceptors in order.", Success, testID)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package tests
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gitamped/seed/auth"
+	"github.com/gitamped/seed/mid"
+	"github.com/gitamped/seed/server"
+	"github.com/gitamped/seed/values"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func Test_Permissions(t *testing.T) {
+	t.Log("Given the need to authorize requests by the permissions of roles")
+	{
+		a := GetAuth()
+
+		// capture records the values of the last request, so the deny
+		// reason can be checked.
+		var captured *values.Values
+		capture := func(h http.HandlerFunc) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				captured, _ = values.GetValues(r.Context())
+				h(w, r)
+			}
+		}
+		mw := append([]mid.Middleware{mid.AuthMiddleware(a)}, mid.CommonMiddleware...)
+		mw = append(mw, capture)
+
+		handler := func(g server.GenericRequest, b []byte) (any, error) {
+			return struct{}{}, nil
+		}
+		register := func(s *server.Server) {
+			s.Register("DocService", "Read", server.RPCEndpoint{Permissions: auth.Requirement{AllOf: []string{"doc:read"}}, Handler: handler})
+			s.Register("DocService", "Delete", server.RPCEndpoint{Permissions: auth.Requirement{AllOf: []string{"doc:delete"}}, Handler: handler})
+			s.Register("DocService", "List", server.RPCEndpoint{Roles: []string{auth.RoleUser}, Handler: handler})
+		}
+
+		s := server.NewServer(mw)
+		s.Permissions = auth.NewPermissions().
+			Grant(auth.RoleUser, "doc:read").
+			Grant(auth.RoleAdmin, "doc:*").
+			Imply(auth.RoleAdmin, auth.RoleUser)
+		register(s)
+
+		unconfigured := server.NewServer(mw)
+		register(unconfigured)
+
+		token := func(roles ...string) string {
+			tok, _ := a.GenerateToken(auth.Claims{
+				RegisteredClaims: jwt.RegisteredClaims{Subject: "alice"},
+				Roles:            roles,
+			})
+			return tok
+		}
+
+		ttable := []struct {
+			TestTitle          string
+			Server             *server.Server
+			Route              string
+			Token              string
+			ExpectedStatusCode int
+			ExpectedReason     string
+		}{
+			{"When a user has the permission", s, "Read", token(auth.RoleUser), http.StatusOK, ""},
+			{"When a user is missing the permission", s, "Delete", token(auth.RoleUser), http.StatusForbidden, "missing permissions: doc:delete"},
+			{"When an admin has the permission by wildcard", s, "Delete", token(auth.RoleAdmin), http.StatusOK, ""},
+			{"When an admin has the role through an implied role", s, "List", token(auth.RoleAdmin), http.StatusOK, ""},
+			{"When an admin reads through an implied role", s, "Read", token(auth.RoleAdmin), http.StatusOK, ""},
+			{"When the server has no permissions configured", unconfigured, "Read", token(auth.RoleAdmin), http.StatusForbidden, "missing permissions: doc:read"},
+			{"When the server has no permissions to imply roles", unconfigured, "List", token(auth.RoleAdmin), http.StatusForbidden, "missing any of roles: USER"},
+		}
+
+		for testID, td := range ttable {
+			t.Logf("\tTest %d:\t%s", testID, td.TestTitle)
+			{
+				r := httptest.NewRequest(http.MethodPost, "/v1/DocService."+td.Route, bytes.NewBufferString(`{}`))
+				r.Header.Set("Authorization", "Bearer "+td.Token)
+				w := httptest.NewRecorder()
+				td.Server.ServeHTTP(w, r)
+
+				if w.Code != td.ExpectedStatusCode {
+					t.Fatalf("\t%s\tTest %d:\tShould receive a status code of %d for the response : %v", Failed, testID, td.ExpectedStatusCode, w.Code)
+				}
+				t.Logf("\t%s\tTest %d:\tShould receive a status code of %d for the response.", Success, testID, td.ExpectedStatusCode)
+
+				if captured == nil || captured.DenyReason != td.ExpectedReason {
+					t.Fatalf("\t%s\tTest %d:\tShould record the deny reason %q : %+v", Failed, testID, td.ExpectedReason, captured)
+				}
+				t.Logf("\t%s\tTest %d:\tShould record the deny reason %q.", Success, testID, td.ExpectedReason)
+			}
+		}
+	}
+}
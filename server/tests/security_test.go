@@ -0,0 +1,153 @@
+package tests
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gitamped/seed/auth"
+	"github.com/gitamped/seed/mid"
+	"github.com/gitamped/seed/server"
+)
+
+func Test_CSRF(t *testing.T) {
+	t.Log("Given the need to protect cookie authenticated clients from forged requests")
+	{
+		csrf := mid.CSRFMiddleware(mid.CSRF{TrustedOrigins: []string{"https://app.example.com"}})
+		mw := append([]mid.Middleware{csrf}, mid.CommonMiddleware...)
+		s := server.NewServer(mw)
+		s.Register("NoteService", "Save", server.RPCEndpoint{
+			Handler: func(g server.GenericRequest, b []byte) (any, error) {
+				return struct{}{}, nil
+			},
+		})
+
+		const token = "double-submit-token"
+
+		ttable := []struct {
+			TestTitle          string
+			Cookie             string
+			Header             string
+			Origin             string
+			Referer            string
+			Authorization      string
+			ExpectedStatusCode int
+		}{
+			{TestTitle: "When the token is missing", ExpectedStatusCode: http.StatusForbidden},
+			{TestTitle: "When the token does not match", Cookie: token, Header: "other", ExpectedStatusCode: http.StatusForbidden},
+			{TestTitle: "When the token matches", Cookie: token, Header: token, ExpectedStatusCode: http.StatusOK},
+			{TestTitle: "When the origin is trusted", Cookie: token, Header: token, Origin: "https://app.example.com", ExpectedStatusCode: http.StatusOK},
+			{TestTitle: "When the origin is not trusted", Cookie: token, Header: token, Origin: "https://evil.io", ExpectedStatusCode: http.StatusForbidden},
+			{TestTitle: "When the referer is not trusted", Cookie: token, Header: token, Referer: "https://evil.io/page", ExpectedStatusCode: http.StatusForbidden},
+			{TestTitle: "When the request is not cookie authenticated", Authorization: "Bearer token", ExpectedStatusCode: http.StatusOK},
+		}
+
+		for testID, td := range ttable {
+			t.Logf("\tTest %d:\t%s", testID, td.TestTitle)
+			{
+				r := httptest.NewRequest(http.MethodPost, "/v1/NoteService.Save", bytes.NewBufferString(`{}`))
+				if td.Cookie != "" {
+					r.AddCookie(&http.Cookie{Name: "csrf_token", Value: td.Cookie})
+				}
+				if td.Header != "" {
+					r.Header.Set("X-CSRF-Token", td.Header)
+				}
+				if td.Origin != "" {
+					r.Header.Set("Origin", td.Origin)
+				}
+				if td.Referer != "" {
+					r.Header.Set("Referer", td.Referer)
+				}
+				if td.Authorization != "" {
+					r.Header.Set("Authorization", td.Authorization)
+				}
+				w := httptest.NewRecorder()
+				s.ServeHTTP(w, r)
+
+				if w.Code != td.ExpectedStatusCode {
+					t.Fatalf("\t%s\tTest %d:\tShould receive a status code of %d for the response : %v", Failed, testID, td.ExpectedStatusCode, w.Code)
+				}
+				t.Logf("\t%s\tTest %d:\tShould receive a status code of %d for the response.", Success, testID, td.ExpectedStatusCode)
+
+				issued := false
+				for _, c := range w.Result().Cookies() {
+					if c.Name == "csrf_token" && c.Value != "" {
+						issued = true
+					}
+				}
+				if issued != (td.Cookie == "") {
+					t.Fatalf("\t%s\tTest %d:\tShould issue a token cookie only when absent : %v", Failed, testID, issued)
+				}
+				t.Logf("\t%s\tTest %d:\tShould issue a token cookie only when absent.", Success, testID)
+			}
+		}
+	}
+}
+
+func Test_SecurityHeaders(t *testing.T) {
+	t.Log("Given the need to set security headers on responses")
+	{
+		mw := append([]mid.Middleware{mid.SecurityHeadersMiddleware}, mid.CommonMiddleware...)
+		s := server.NewServer(mw)
+		NewSecretGreeterServicer().Register(s)
+
+		r := httptest.NewRequest(http.MethodPost, "/v1/GreeterService.SecretGreet", bytes.NewBufferString(`{}`))
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+
+		for testID, name := range []string{"Strict-Transport-Security", "X-Content-Type-Options", "Content-Security-Policy", "X-Frame-Options", "Referrer-Policy"} {
+			t.Logf("\tTest %d:\tWhen checking the %s header", testID, name)
+			{
+				if got := w.Header().Get(name); got != mid.SecurityHeaders[name] {
+					t.Fatalf("\t%s\tTest %d:\tShould set %s to %q : %q", Failed, testID, name, mid.SecurityHeaders[name], got)
+				}
+				t.Logf("\t%s\tTest %d:\tShould set %s.", Success, testID, name)
+			}
+		}
+	}
+}
+
+func Test_CookieToken(t *testing.T) {
+	t.Log("Given the need to authenticate with a token in a cookie")
+	{
+		a := GetAuth()
+		mw := append([]mid.Middleware{mid.AuthMiddleware(a, mid.CookieToken("access_token"))}, mid.CommonMiddleware...)
+		s := server.NewServer(mw)
+		s.Register("AdminService", "Call", server.RPCEndpoint{
+			Roles: []string{auth.RoleAdmin},
+			Handler: func(g server.GenericRequest, b []byte) (any, error) {
+				return struct{}{}, nil
+			},
+		})
+
+		token, _ := a.GenerateToken(auth.Claims{Roles: []string{auth.RoleAdmin}})
+
+		ttable := []struct {
+			TestTitle          string
+			Cookie             string
+			ExpectedStatusCode int
+		}{
+			{"When the cookie holds a valid token", token, http.StatusOK},
+			{"When the cookie holds an invalid token", "invalid", http.StatusUnauthorized},
+			{"When there is no cookie", "", http.StatusUnauthorized},
+		}
+
+		for testID, td := range ttable {
+			t.Logf("\tTest %d:\t%s", testID, td.TestTitle)
+			{
+				r := httptest.NewRequest(http.MethodPost, "/v1/AdminService.Call", bytes.NewBufferString(`{}`))
+				if td.Cookie != "" {
+					r.AddCookie(&http.Cookie{Name: "access_token", Value: td.Cookie})
+				}
+				w := httptest.NewRecorder()
+				s.ServeHTTP(w, r)
+
+				if w.Code != td.ExpectedStatusCode {
+					t.Fatalf("\t%s\tTest %d:\tShould receive a status code of %d for the response : %v", Failed, testID, td.ExpectedStatusCode, w.Code)
+				}
+				t.Logf("\t%s\tTest %d:\tShould receive a status code of %d for the response.", Success, testID, td.ExpectedStatusCode)
+			}
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package tests
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gitamped/seed/auth"
+	"github.com/gitamped/seed/mid"
+	"github.com/gitamped/seed/server"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func Test_AuthStatus(t *testing.T) {
+	t.Log("Given the need to tell unauthenticated from unauthorized callers")
+	{
+		a := GetAuth()
+		mw := append([]mid.Middleware{mid.AuthMiddleware(a)}, mid.CommonMiddleware...)
+		s := server.NewServer(mw)
+		s.Register("AdminService", "Call", server.RPCEndpoint{
+			Roles: []string{auth.RoleAdmin},
+			Handler: func(g server.GenericRequest, b []byte) (any, error) {
+				return struct{}{}, nil
+			},
+		})
+
+		token := func(exp time.Duration) string {
+			tkn, _ := a.GenerateToken(auth.Claims{
+				RegisteredClaims: jwt.RegisteredClaims{
+					Subject:   "5cf37266-3473-4006-984f-9325122678b7",
+					ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(exp)),
+				},
+				Roles: []string{auth.RoleUser},
+			})
+			return tkn
+		}
+
+		ttable := []struct {
+			TestTitle          string
+			Token              string
+			ExpectedStatusCode int
+			ExpectedChallenge  string
+		}{
+			{"When no token is sent", "", http.StatusUnauthorized, `Bearer realm="seed"`},
+			{"When the token is expired", token(-time.Hour), http.StatusUnauthorized, `error_description="token is expired"`},
+			{"When the user lacks the role", token(time.Hour), http.StatusForbidden, `error="insufficient_scope"`},
+		}
+
+		for testID, td := range ttable {
+			t.Logf("\tTest %d:\t%s", testID, td.TestTitle)
+			{
+				r := httptest.NewRequest(http.MethodPost, "/v1/AdminService.Call", bytes.NewBufferString(`{}`))
+				if td.Token != "" {
+					r.Header.Set("Authorization", "Bearer "+td.Token)
+				}
+				w := httptest.NewRecorder()
+				s.ServeHTTP(w, r)
+
+				if w.Code != td.ExpectedStatusCode {
+					t.Fatalf("\t%s\tTest %d:\tShould receive a status code of %d for the response : %v", Failed, testID, td.ExpectedStatusCode, w.Code)
+				}
+				t.Logf("\t%s\tTest %d:\tShould receive a status code of %d for the response.", Success, testID, td.ExpectedStatusCode)
+
+				if got := w.Header().Get("WWW-Authenticate"); !strings.Contains(got, td.ExpectedChallenge) {
+					t.Fatalf("\t%s\tTest %d:\tShould challenge with %s : %s", Failed, testID, td.ExpectedChallenge, got)
+				}
+				t.Logf("\t%s\tTest %d:\tShould challenge with %s.", Success, testID, td.ExpectedChallenge)
+			}
+		}
+	}
+}
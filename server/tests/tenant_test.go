@@ -0,0 +1,82 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gitamped/seed/auth"
+	"github.com/gitamped/seed/mid"
+	"github.com/gitamped/seed/server"
+)
+
+func Test_TenantIsolation(t *testing.T) {
+	t.Log("Given the need to isolate tenants")
+	{
+		a := GetAuth()
+		mw := append([]mid.Middleware{mid.AuthMiddleware(a)}, mid.CommonMiddleware...)
+		s := server.NewServer(mw)
+		s.Register("TenantService", "Update", server.RPCEndpoint{
+			Roles:        []string{auth.RoleAdmin},
+			TenantScoped: true,
+			Handler: func(g server.GenericRequest, b []byte) (any, error) {
+				var req struct {
+					Tenant string `json:"tenant"`
+				}
+				if err := json.Unmarshal(b, &req); err != nil {
+					return nil, err
+				}
+				return struct{ Tenant string }{req.Tenant}, nil
+			},
+		})
+		s.Register("AdminService", "Delete", server.RPCEndpoint{
+			Roles: []string{auth.RoleAdmin},
+			Handler: func(g server.GenericRequest, b []byte) (any, error) {
+				return struct{}{}, nil
+			},
+		})
+
+		// The caller is only an admin within the acme tenant.
+		token, _ := a.GenerateToken(auth.Claims{
+			Roles:       []string{auth.RoleUser},
+			Tenant:      "acme",
+			TenantRoles: map[string][]string{"acme": {auth.RoleAdmin}},
+		})
+
+		ttable := []struct {
+			TestTitle          string
+			Route              string
+			RequestData        string
+			ExpectedStatusCode int
+		}{
+			{"When acting in the caller's tenant", "TenantService.Update", `{"tenant": "acme"}`, http.StatusOK},
+			{"When the tenant member has another case", "TenantService.Update", `{"Tenant": "acme"}`, http.StatusOK},
+			{"When acting in another tenant", "TenantService.Update", `{"tenant": "globex"}`, http.StatusForbidden},
+			{"When the request has no tenant", "TenantService.Update", `{}`, http.StatusForbidden},
+			{"When tenant members only differ in case", "TenantService.Update", `{"tenant": "acme", "Tenant": "globex"}`, http.StatusBadRequest},
+			{"When calling an endpoint that is not tenant scoped", "AdminService.Delete", `{"tenant": "acme"}`, http.StatusForbidden},
+		}
+
+		for testID, td := range ttable {
+			t.Logf("\tTest %d:\t%s", testID, td.TestTitle)
+			{
+				r := httptest.NewRequest(http.MethodPost, "/v1/"+td.Route, bytes.NewBufferString(td.RequestData))
+				r.Header.Set("Authorization", "Bearer "+token)
+				w := httptest.NewRecorder()
+				s.ServeHTTP(w, r)
+
+				if w.Code != td.ExpectedStatusCode {
+					t.Fatalf("\t%s\tTest %d:\tShould receive a status code of %d for the response : %v", Failed, testID, td.ExpectedStatusCode, w.Code)
+				}
+				t.Logf("\t%s\tTest %d:\tShould receive a status code of %d for the response.", Success, testID, td.ExpectedStatusCode)
+
+				var resp struct{ Tenant string }
+				if td.Route == "TenantService.Update" && w.Code == http.StatusOK && (json.Unmarshal(w.Body.Bytes(), &resp) != nil || resp.Tenant != "acme") {
+					t.Fatalf("\t%s\tTest %d:\tShould act in the caller's tenant : %s", Failed, testID, w.Body.String())
+				}
+			}
+		}
+	}
+}
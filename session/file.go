@@ -0,0 +1,69 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+
+	"github.com/gitamped/seed/internal/jsonfile"
+)
+
+// FileStore is a MemoryStore persisted as JSON to a file, so sessions
+// survive restarts.
+type FileStore struct {
+	*MemoryStore
+	path string
+}
+
+// Open constructs a FileStore, loading the sessions in the file at path if
+// it exists.
+func Open(path string) (*FileStore, error) {
+	f := FileStore{
+		MemoryStore: NewMemoryStore(),
+		path:        path,
+	}
+
+	b, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return &f, nil
+	case err != nil:
+		return nil, fmt.Errorf("reading sessions: %w", err)
+	}
+
+	if err := json.Unmarshal(b, &f.sessions); err != nil {
+		return nil, fmt.Errorf("decoding sessions: %w", err)
+	}
+
+	return &f, nil
+}
+
+// Save implements the Store interface and saves the store.
+func (f *FileStore) Save(ctx context.Context, s Session) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.save(s, time.Now())
+	return f.write()
+}
+
+// Delete implements the Store interface and saves the store.
+func (f *FileStore) Delete(ctx context.Context, id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.sessions, id)
+	return f.write()
+}
+
+// write atomically writes the store to its file. The caller must hold mu.
+func (f *FileStore) write() error {
+	if err := jsonfile.Write(f.path, f.sessions); err != nil {
+		return fmt.Errorf("saving sessions: %w", err)
+	}
+	return nil
+}
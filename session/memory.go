@@ -0,0 +1,61 @@
+package session
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore is an in-memory session store.
+type MemoryStore struct {
+	mu       sync.RWMutex
+	sessions map[string]Session
+}
+
+// NewMemoryStore constructs an empty MemoryStore ready for use.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		sessions: make(map[string]Session),
+	}
+}
+
+// Get implements the Store interface.
+func (ms *MemoryStore) Get(ctx context.Context, id string) (Session, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	s, ok := ms.sessions[id]
+	if !ok {
+		return Session{}, ErrNotFound
+	}
+	return s, nil
+}
+
+// Save implements the Store interface. Expired sessions are dropped.
+func (ms *MemoryStore) Save(ctx context.Context, s Session) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ms.save(s, time.Now())
+	return nil
+}
+
+// Delete implements the Store interface.
+func (ms *MemoryStore) Delete(ctx context.Context, id string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	delete(ms.sessions, id)
+	return nil
+}
+
+// save stores the session and drops expired sessions. The caller must hold
+// mu.
+func (ms *MemoryStore) save(s Session, now time.Time) {
+	for id, old := range ms.sessions {
+		if !now.Before(old.Expires) {
+			delete(ms.sessions, id)
+		}
+	}
+	ms.sessions[s.ID] = s
+}
@@ -0,0 +1,223 @@
+// Package session authenticates browser clients with signed session cookies
+// backed by server-side session state.
+package session
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gitamped/seed/auth"
+)
+
+// Set of errors returned for rejected sessions.
+var (
+	ErrNotFound      = errors.New("session not found")
+	ErrExpired       = errors.New("session expired")
+	ErrInvalidCookie = errors.New("session cookie is invalid")
+)
+
+// Session is the server-side state of a session.
+type Session struct {
+	ID       string      `json:"id"`
+	Claims   auth.Claims `json:"claims"`
+	Created  time.Time   `json:"created"`
+	LastSeen time.Time   `json:"last_seen"`
+	// Expires is when the session ends regardless of activity.
+	Expires time.Time `json:"expires"`
+}
+
+// Store declares a method set of behavior for keeping session state. Stores
+// may drop sessions once they have expired.
+type Store interface {
+	Get(ctx context.Context, id string) (Session, error)
+	Save(ctx context.Context, s Session) error
+	Delete(ctx context.Context, id string) error
+}
+
+// Config configures a Manager.
+type Config struct {
+	// Secret signs session cookies. It must be at least auth.MinSecretSize
+	// bytes.
+	Secret []byte
+	// CookieName is the name of the session cookie. Default: session
+	CookieName string
+	// Path scopes the session cookie. Default: /
+	Path string
+	// Secure restricts the session cookie to https.
+	Secure bool
+	// IdleTimeout ends sessions that are not used. Default: 30m
+	IdleTimeout time.Duration
+	// AbsoluteTimeout ends sessions regardless of activity. Default: 12h
+	AbsoluteTimeout time.Duration
+}
+
+// Manager issues and checks session cookies.
+type Manager struct {
+	store      Store
+	cfg        Config
+	touchEvery time.Duration
+}
+
+// New constructs a Manager keeping sessions in the store.
+func New(store Store, cfg Config) (*Manager, error) {
+	if len(cfg.Secret) < auth.MinSecretSize {
+		return nil, fmt.Errorf("secret must be at least %d bytes", auth.MinSecretSize)
+	}
+	if cfg.CookieName == "" {
+		cfg.CookieName = "session"
+	}
+	if cfg.Path == "" {
+		cfg.Path = "/"
+	}
+	if cfg.IdleTimeout <= 0 {
+		cfg.IdleTimeout = 30 * time.Minute
+	}
+	if cfg.AbsoluteTimeout <= 0 {
+		cfg.AbsoluteTimeout = 12 * time.Hour
+	}
+
+	// Saving the last seen time on every request is wasteful, so it is
+	// saved at most once per tenth of the idle timeout.
+	touchEvery := cfg.IdleTimeout / 10
+	if touchEvery > time.Minute {
+		touchEvery = time.Minute
+	}
+
+	m := Manager{
+		store:      store,
+		cfg:        cfg,
+		touchEvery: touchEvery,
+	}
+
+	return &m, nil
+}
+
+// Create starts a session for the claims and sets its cookie.
+func (m *Manager) Create(ctx context.Context, w http.ResponseWriter, claims auth.Claims) (Session, error) {
+	now := time.Now()
+	s := Session{
+		Claims:  claims,
+		Created: now,
+		Expires: now.Add(m.cfg.AbsoluteTimeout),
+	}
+	return m.issue(ctx, w, s, now)
+}
+
+// Rotate moves the session to a new id with the claims and sets its
+// cookie. Call it whenever the privileges of the session change, such as on
+// login or a change of roles, so an id known before the change is useless.
+// The absolute timeout of the session is kept.
+func (m *Manager) Rotate(ctx context.Context, w http.ResponseWriter, s Session, claims auth.Claims) (Session, error) {
+	if err := m.store.Delete(ctx, s.ID); err != nil && !errors.Is(err, ErrNotFound) {
+		return Session{}, fmt.Errorf("deleting session: %w", err)
+	}
+	s.Claims = claims
+	return m.issue(ctx, w, s, time.Now())
+}
+
+// Load returns the session of the request. It returns false if the request
+// has no session cookie.
+func (m *Manager) Load(r *http.Request) (Session, bool, error) {
+	c, err := r.Cookie(m.cfg.CookieName)
+	if err != nil {
+		return Session{}, false, nil
+	}
+
+	id, ok := m.verify(c.Value)
+	if !ok {
+		return Session{}, true, ErrInvalidCookie
+	}
+
+	ctx := r.Context()
+	s, err := m.store.Get(ctx, id)
+	if err != nil {
+		return Session{}, true, err
+	}
+
+	now := time.Now()
+	if !now.Before(s.Expires) || now.Sub(s.LastSeen) >= m.cfg.IdleTimeout {
+		m.store.Delete(ctx, id)
+		return Session{}, true, ErrExpired
+	}
+
+	if now.Sub(s.LastSeen) >= m.touchEvery {
+		s.LastSeen = now
+		if err := m.store.Save(ctx, s); err != nil {
+			return Session{}, true, fmt.Errorf("saving session: %w", err)
+		}
+	}
+
+	return s, true, nil
+}
+
+// Destroy ends the session of the request and clears its cookie.
+func (m *Manager) Destroy(w http.ResponseWriter, r *http.Request) error {
+	http.SetCookie(w, m.cookie("", -1))
+
+	c, err := r.Cookie(m.cfg.CookieName)
+	if err != nil {
+		return nil
+	}
+	id, ok := m.verify(c.Value)
+	if !ok {
+		return nil
+	}
+	if err := m.store.Delete(r.Context(), id); err != nil && !errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("deleting session: %w", err)
+	}
+	return nil
+}
+
+// issue saves the session under a new id and sets its cookie.
+func (m *Manager) issue(ctx context.Context, w http.ResponseWriter, s Session, now time.Time) (Session, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return Session{}, fmt.Errorf("generating session id: %w", err)
+	}
+	s.ID = base64.RawURLEncoding.EncodeToString(b)
+	s.LastSeen = now
+
+	if err := m.store.Save(ctx, s); err != nil {
+		return Session{}, fmt.Errorf("saving session: %w", err)
+	}
+
+	http.SetCookie(w, m.cookie(s.ID+"."+m.sign(s.ID), int(s.Expires.Sub(now).Seconds())))
+	return s, nil
+}
+
+// cookie returns the session cookie with the value.
+func (m *Manager) cookie(value string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     m.cfg.CookieName,
+		Value:    value,
+		Path:     m.cfg.Path,
+		MaxAge:   maxAge,
+		Secure:   m.cfg.Secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// sign returns the signature of the session id.
+func (m *Manager) sign(id string) string {
+	mac := hmac.New(sha256.New, m.cfg.Secret)
+	mac.Write([]byte(id))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// verify returns the session id of a cookie value with a valid signature.
+func (m *Manager) verify(value string) (string, bool) {
+	id, sig, ok := strings.Cut(value, ".")
+	if !ok || id == "" {
+		return "", false
+	}
+	return id, hmac.Equal([]byte(sig), []byte(m.sign(id)))
+}
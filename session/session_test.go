@@ -0,0 +1,189 @@
+package session_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gitamped/seed/auth"
+	"github.com/gitamped/seed/mid"
+	"github.com/gitamped/seed/server"
+	"github.com/gitamped/seed/session"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+var secret = []byte("0123456789abcdef0123456789abcdef")
+
+func Test_Session(t *testing.T) {
+	t.Log("Given the need to authenticate browsers with sessions.")
+	{
+		m, err := session.New(session.NewMemoryStore(), session.Config{Secret: secret})
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to create a manager : %v", failed, err)
+		}
+
+		mw := append([]mid.Middleware{mid.SessionMiddleware(m)}, mid.CommonMiddleware...)
+		s := server.NewServer(mw)
+		s.Register("AdminService", "Get", server.RPCEndpoint{
+			Roles: []string{auth.RoleAdmin},
+			Handler: func(g server.GenericRequest, b []byte) (any, error) {
+				return struct{ Subject string }{g.Subject()}, nil
+			},
+		})
+
+		call := func(c *http.Cookie) int {
+			r := httptest.NewRequest(http.MethodPost, "/v1/AdminService.Get", bytes.NewBufferString(`{}`))
+			if c != nil {
+				r.AddCookie(c)
+			}
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, r)
+			return w.Code
+		}
+
+		// Log in as a user, then become an admin.
+		w := httptest.NewRecorder()
+		user := auth.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "user"}, Roles: []string{auth.RoleUser}}
+		sess, err := m.Create(context.Background(), w, user)
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to create a session : %v", failed, err)
+		}
+		userCookie := w.Result().Cookies()[0]
+		if !userCookie.HttpOnly {
+			t.Fatalf("\t%s\tShould set an httpOnly cookie.", failed)
+		}
+
+		w = httptest.NewRecorder()
+		admin := auth.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "user"}, Roles: []string{auth.RoleAdmin}}
+		if _, err := m.Rotate(context.Background(), w, sess, admin); err != nil {
+			t.Fatalf("\t%s\tShould be able to rotate the session : %v", failed, err)
+		}
+		adminCookie := w.Result().Cookies()[0]
+
+		tampered := *adminCookie
+		tampered.Value = userCookie.Value[:len(userCookie.Value)-1] + "x"
+
+		ttable := []struct {
+			TestTitle          string
+			Cookie             *http.Cookie
+			ExpectedStatusCode int
+		}{
+			{"When the session has the role", adminCookie, http.StatusOK},
+			{"When using the session id before rotation", userCookie, http.StatusUnauthorized},
+			{"When the cookie is tampered with", &tampered, http.StatusUnauthorized},
+			{"When there is no session", nil, http.StatusUnauthorized},
+		}
+
+		for testID, td := range ttable {
+			t.Logf("\tTest %d:\t%s.", testID, td.TestTitle)
+			{
+				if code := call(td.Cookie); code != td.ExpectedStatusCode {
+					t.Fatalf("\t%s\tTest %d:\tShould receive a status code of %d for the response : %v", failed, testID, td.ExpectedStatusCode, code)
+				}
+				t.Logf("\t%s\tTest %d:\tShould receive a status code of %d for the response.", success, testID, td.ExpectedStatusCode)
+			}
+		}
+
+		testID := len(ttable)
+		t.Logf("\tTest %d:\tWhen the session is destroyed.", testID)
+		{
+			r := httptest.NewRequest(http.MethodPost, "/logout", nil)
+			r.AddCookie(adminCookie)
+			if err := m.Destroy(httptest.NewRecorder(), r); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to destroy the session : %v", failed, testID, err)
+			}
+			if code := call(adminCookie); code != http.StatusUnauthorized {
+				t.Fatalf("\t%s\tTest %d:\tShould reject the session : %v", failed, testID, code)
+			}
+			t.Logf("\t%s\tTest %d:\tShould reject the session.", success, testID)
+		}
+	}
+}
+
+func Test_Timeouts(t *testing.T) {
+	t.Log("Given the need to end sessions that are not used.")
+	{
+		m, _ := session.New(session.NewMemoryStore(), session.Config{
+			Secret:          secret,
+			IdleTimeout:     50 * time.Millisecond,
+			AbsoluteTimeout: 200 * time.Millisecond,
+		})
+
+		w := httptest.NewRecorder()
+		if _, err := m.Create(context.Background(), w, auth.Claims{}); err != nil {
+			t.Fatalf("\t%s\tShould be able to create a session : %v", failed, err)
+		}
+		cookie := w.Result().Cookies()[0]
+
+		load := func() error {
+			r := httptest.NewRequest(http.MethodPost, "/", nil)
+			r.AddCookie(cookie)
+			_, _, err := m.Load(r)
+			return err
+		}
+
+		t.Logf("\tTest %d:\tWhen the session is used within the idle timeout.", 0)
+		{
+			for i := 0; i < 3; i++ {
+				time.Sleep(30 * time.Millisecond)
+				if err := load(); err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould keep the session : %v", failed, 0, err)
+				}
+			}
+			t.Logf("\t%s\tTest %d:\tShould keep the session.", success, 0)
+		}
+
+		t.Logf("\tTest %d:\tWhen the session is idle.", 1)
+		{
+			time.Sleep(60 * time.Millisecond)
+			if err := load(); !errors.Is(err, session.ErrExpired) {
+				t.Fatalf("\t%s\tTest %d:\tShould end the session : %v", failed, 1, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould end the session.", success, 1)
+		}
+	}
+}
+
+func Test_FileStore(t *testing.T) {
+	t.Log("Given the need to keep sessions across restarts.")
+	{
+		path := filepath.Join(t.TempDir(), "sessions.json")
+		fs, err := session.Open(path)
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to open the store : %v", failed, err)
+		}
+
+		s := session.Session{
+			ID:      "id",
+			Claims:  auth.Claims{Roles: []string{auth.RoleAdmin}},
+			Expires: time.Now().Add(time.Hour),
+		}
+		if err := fs.Save(context.Background(), s); err != nil {
+			t.Fatalf("\t%s\tShould be able to save a session : %v", failed, err)
+		}
+
+		t.Logf("\tTest %d:\tWhen the store is reopened.", 0)
+		{
+			fs, err := session.Open(path)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to reopen the store : %v", failed, 0, err)
+			}
+			got, err := fs.Get(context.Background(), "id")
+			if err != nil || !got.Claims.Authorized(auth.RoleAdmin) {
+				t.Fatalf("\t%s\tTest %d:\tShould load the session : %v %+v", failed, 0, err, got)
+			}
+			t.Logf("\t%s\tTest %d:\tShould load the session.", success, 0)
+		}
+	}
+}
@@ -0,0 +1,326 @@
+// Package tokens provides an rpc service issuing access tokens and rotating
+// refresh tokens.
+package tokens
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gitamped/seed/auth"
+	"github.com/gitamped/seed/server"
+	"github.com/gitamped/seed/validate"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// ErrInvalidCredentials is returned by an Authenticator when the
+// credentials do not match.
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// Credentials are presented by a client to log in.
+type Credentials struct {
+	Username string
+	Password string
+}
+
+// Authenticator declares a method set of behavior for checking credentials.
+// It returns the claims, at least Subject and Roles, of the caller.
+type Authenticator interface {
+	Authenticate(ctx context.Context, c Credentials) (auth.Claims, error)
+}
+
+// ClaimsLoader may be implemented by an Authenticator to return the current
+// claims of a user, so refreshed access tokens pick up changes such as new
+// roles. It returns ErrInvalidCredentials if the user may no longer log in.
+type ClaimsLoader interface {
+	LoadClaims(ctx context.Context, username string) (auth.Claims, error)
+}
+
+// Config configures the TokenService.
+type Config struct {
+	// Issuer is set as the iss claim of access tokens.
+	Issuer string
+	// Audience is set as the aud claim of access tokens.
+	Audience []string
+	// AccessTTL is the lifetime of access tokens. Default: 15m
+	AccessTTL time.Duration
+	// RefreshTTL is the lifetime of refresh tokens. Default: 30 days
+	RefreshTTL time.Duration
+}
+
+// TokenServicer implements the TokenService rpc service.
+type TokenServicer struct {
+	auth  *auth.Auth
+	authn Authenticator
+	store RefreshStore
+	cfg   Config
+}
+
+// NewTokenServicer constructs a TokenServicer.
+func NewTokenServicer(a *auth.Auth, authn Authenticator, store RefreshStore, cfg Config) *TokenServicer {
+	if cfg.AccessTTL <= 0 {
+		cfg.AccessTTL = 15 * time.Minute
+	}
+	if cfg.RefreshTTL <= 0 {
+		cfg.RefreshTTL = 30 * 24 * time.Hour
+	}
+
+	return &TokenServicer{
+		auth:  a,
+		authn: authn,
+		store: store,
+		cfg:   cfg,
+	}
+}
+
+// Register implements the server.RPCService interface.
+func (ts *TokenServicer) Register(s *server.Server) {
+	s.Register("TokenService", "Login", server.RPCEndpoint{Roles: []string{}, Handler: ts.LoginHandler})
+	s.Register("TokenService", "Refresh", server.RPCEndpoint{Roles: []string{}, Handler: ts.RefreshHandler})
+	s.Register("TokenService", "Logout", server.RPCEndpoint{Roles: []string{}, Handler: ts.LogoutHandler})
+}
+
+// LoginRequest is the request object for TokenService.Login.
+type LoginRequest struct {
+	Username string `json:"username" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+// RefreshRequest is the request object for TokenService.Refresh and
+// TokenService.Logout.
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+// TokenResponse is the response object containing issued tokens.
+type TokenResponse struct {
+	AccessToken  string `json:"access_token,omitempty"`
+	TokenType    string `json:"token_type,omitempty"`
+	ExpiresIn    int    `json:"expires_in,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	// Error message if request was not successful
+	Error string `json:"error,omitempty"`
+}
+
+// LoginHandler validates input data prior to calling Login.
+func (ts *TokenServicer) LoginHandler(g server.GenericRequest, b []byte) (any, error) {
+	var req LoginRequest
+	if err := json.Unmarshal(b, &req); err != nil {
+		return TokenResponse{Error: "Invalid LoginRequest data."}, nil
+	}
+	if err := validate.Check(req); err != nil {
+		return TokenResponse{Error: fmt.Errorf("validating data: %w", err).Error()}, nil
+	}
+
+	return ts.Login(g.Ctx, req)
+}
+
+// Login checks the credentials and issues a new token pair.
+func (ts *TokenServicer) Login(ctx context.Context, req LoginRequest) (TokenResponse, error) {
+	claims, err := ts.authn.Authenticate(ctx, Credentials{Username: req.Username, Password: req.Password})
+	if err != nil {
+		if errors.Is(err, ErrInvalidCredentials) {
+			return TokenResponse{Error: ErrInvalidCredentials.Error()}, nil
+		}
+		return TokenResponse{}, fmt.Errorf("authenticating: %w", err)
+	}
+
+	family, err := randomString(16)
+	if err != nil {
+		return TokenResponse{}, err
+	}
+
+	l := login{family: family, username: req.Username, at: time.Now().UTC()}
+	return ts.issue(ctx, claims, l)
+}
+
+// RefreshHandler validates input data prior to calling Refresh.
+func (ts *TokenServicer) RefreshHandler(g server.GenericRequest, b []byte) (any, error) {
+	var req RefreshRequest
+	if err := json.Unmarshal(b, &req); err != nil {
+		return TokenResponse{Error: "Invalid RefreshRequest data."}, nil
+	}
+	if err := validate.Check(req); err != nil {
+		return TokenResponse{Error: fmt.Errorf("validating data: %w", err).Error()}, nil
+	}
+
+	return ts.Refresh(g.Ctx, req)
+}
+
+// Refresh exchanges a refresh token for a new token pair. Each refresh
+// token can be used once; presenting a used token revokes every token
+// rotated from the same login. Logins revoked through the revocation store
+// of the Auth can not be refreshed, and the claims are reloaded when the
+// Authenticator is a ClaimsLoader.
+func (ts *TokenServicer) Refresh(ctx context.Context, req RefreshRequest) (TokenResponse, error) {
+	rt, err := ts.lookup(ctx, req.RefreshToken)
+	if err != nil {
+		return TokenResponse{Error: err.Error()}, nil
+	}
+
+	// The login is checked before the token is marked used, so a retry
+	// after an error loading the claims is not taken for reuse. Every token
+	// of the subject issued before a revocation is revoked, so the login of
+	// the family is checked, not the time of the last refresh.
+	revoked := rt.Claims
+	revoked.ID = ""
+	revoked.IssuedAt = jwt.NewNumericDate(rt.LoginAt)
+	if err := ts.auth.CheckRevoked(revoked); err != nil {
+		return ts.reject(ctx, rt, err)
+	}
+
+	claims := rt.Claims
+	if l, ok := ts.authn.(ClaimsLoader); ok {
+		if claims, err = l.LoadClaims(ctx, rt.Username); err != nil {
+			return ts.reject(ctx, rt, err)
+		}
+	}
+
+	first, err := ts.store.MarkUsed(ctx, rt.ID)
+	if err != nil {
+		return TokenResponse{}, fmt.Errorf("marking refresh token used: %w", err)
+	}
+	if !first {
+		if err := ts.store.RevokeFamily(ctx, rt.Family); err != nil {
+			return TokenResponse{}, fmt.Errorf("revoking refresh tokens: %w", err)
+		}
+		return TokenResponse{Error: "refresh token reuse detected"}, nil
+	}
+
+	l := login{family: rt.Family, username: rt.Username, at: rt.LoginAt}
+	return ts.issue(ctx, claims, l)
+}
+
+// reject revokes the family of a refresh token that may no longer be used.
+// Other errors are returned as is, leaving the family intact.
+func (ts *TokenServicer) reject(ctx context.Context, rt RefreshToken, err error) (TokenResponse, error) {
+	if !errors.Is(err, auth.ErrTokenRevoked) && !errors.Is(err, ErrInvalidCredentials) {
+		return TokenResponse{}, fmt.Errorf("checking refresh token: %w", err)
+	}
+	if err := ts.store.RevokeFamily(ctx, rt.Family); err != nil {
+		return TokenResponse{}, fmt.Errorf("revoking refresh tokens: %w", err)
+	}
+	return TokenResponse{Error: "refresh token revoked"}, nil
+}
+
+// LogoutHandler validates input data prior to calling Logout.
+func (ts *TokenServicer) LogoutHandler(g server.GenericRequest, b []byte) (any, error) {
+	var req RefreshRequest
+	if err := json.Unmarshal(b, &req); err != nil {
+		return TokenResponse{Error: "Invalid RefreshRequest data."}, nil
+	}
+	if err := validate.Check(req); err != nil {
+		return TokenResponse{Error: fmt.Errorf("validating data: %w", err).Error()}, nil
+	}
+
+	return ts.Logout(g.Ctx, req)
+}
+
+// Logout revokes the refresh token and every token rotated with it.
+func (ts *TokenServicer) Logout(ctx context.Context, req RefreshRequest) (TokenResponse, error) {
+	rt, err := ts.lookup(ctx, req.RefreshToken)
+	if err != nil {
+		return TokenResponse{Error: err.Error()}, nil
+	}
+	if err := ts.store.RevokeFamily(ctx, rt.Family); err != nil {
+		return TokenResponse{}, fmt.Errorf("revoking refresh tokens: %w", err)
+	}
+	return TokenResponse{}, nil
+}
+
+// login identifies the login a refresh token family was issued for.
+type login struct {
+	family   string
+	username string
+	at       time.Time
+}
+
+// issue signs an access token and stores a new refresh token for the login.
+func (ts *TokenServicer) issue(ctx context.Context, claims auth.Claims, l login) (TokenResponse, error) {
+	now := time.Now().UTC()
+
+	access := claims
+	access.Issuer = ts.cfg.Issuer
+	access.Audience = ts.cfg.Audience
+	access.IssuedAt = jwt.NewNumericDate(now)
+	access.NotBefore = nil
+	access.ExpiresAt = jwt.NewNumericDate(now.Add(ts.cfg.AccessTTL))
+
+	token, err := ts.auth.GenerateToken(access)
+	if err != nil {
+		return TokenResponse{}, fmt.Errorf("generating access token: %w", err)
+	}
+
+	id, err := randomString(16)
+	if err != nil {
+		return TokenResponse{}, err
+	}
+	secret, err := randomString(32)
+	if err != nil {
+		return TokenResponse{}, err
+	}
+	hash := sha256.Sum256([]byte(secret))
+
+	rt := RefreshToken{
+		ID:        id,
+		Family:    l.family,
+		Username:  l.username,
+		LoginAt:   l.at,
+		Hash:      hash[:],
+		Claims:    claims,
+		ExpiresAt: now.Add(ts.cfg.RefreshTTL),
+	}
+	if err := ts.store.Create(ctx, rt); err != nil {
+		return TokenResponse{}, fmt.Errorf("storing refresh token: %w", err)
+	}
+
+	resp := TokenResponse{
+		AccessToken:  token,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(ts.cfg.AccessTTL.Seconds()),
+		RefreshToken: id + "." + secret,
+	}
+
+	return resp, nil
+}
+
+// lookup returns the stored refresh token if it is valid.
+func (ts *TokenServicer) lookup(ctx context.Context, token string) (RefreshToken, error) {
+	invalid := errors.New("invalid refresh token")
+
+	id, secret, ok := strings.Cut(token, ".")
+	if !ok {
+		return RefreshToken{}, invalid
+	}
+
+	rt, err := ts.store.Get(ctx, id)
+	if err != nil {
+		return RefreshToken{}, invalid
+	}
+
+	hash := sha256.Sum256([]byte(secret))
+	if subtle.ConstantTimeCompare(hash[:], rt.Hash) != 1 {
+		return RefreshToken{}, invalid
+	}
+	if rt.Revoked || time.Now().After(rt.ExpiresAt) {
+		return RefreshToken{}, invalid
+	}
+
+	return rt, nil
+}
+
+// randomString returns n random bytes, base64url encoded.
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generating random bytes: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
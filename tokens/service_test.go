@@ -0,0 +1,180 @@
+package tokens_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gitamped/seed/auth"
+	"github.com/gitamped/seed/denylist"
+	"github.com/gitamped/seed/keystore"
+	"github.com/gitamped/seed/mid"
+	"github.com/gitamped/seed/server"
+	"github.com/gitamped/seed/tokens"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+func Test_TokenService(t *testing.T) {
+	t.Log("Given the need to issue and renew tokens.")
+	{
+		privateKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+		ks := keystore.New()
+		ks.Add(privateKey, "kid")
+		a, err := auth.New("kid", ks)
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to create an authenticator: %v", failed, err)
+		}
+
+		s := server.NewServer(mid.CommonMiddleware)
+		revocations := denylist.New()
+		a.SetRevocation(revocations)
+		authn := &authenticator{roles: []string{auth.RoleUser}}
+		tokens.NewTokenServicer(a, authn, tokens.NewMemoryStore(), tokens.Config{Issuer: "seed project"}).Register(s)
+
+		call := func(route string, req any) tokens.TokenResponse {
+			b, _ := json.Marshal(req)
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/TokenService."+route, bytes.NewBuffer(b)))
+
+			var resp tokens.TokenResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("\t%s\tShould be able to unmarshal the response : %v", failed, err)
+			}
+			return resp
+		}
+
+		testID := 0
+		t.Logf("\tTest %d:\tWhen logging in.", testID)
+		var login tokens.TokenResponse
+		{
+			if resp := call("Login", tokens.LoginRequest{Username: "seed", Password: "wrong"}); resp.Error == "" {
+				t.Fatalf("\t%s\tTest %d:\tShould reject invalid credentials.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould reject invalid credentials.", success, testID)
+
+			login = call("Login", tokens.LoginRequest{Username: "seed", Password: "secret"})
+			claims, err := a.ValidateToken(login.AccessToken)
+			if err != nil || login.RefreshToken == "" {
+				t.Fatalf("\t%s\tTest %d:\tShould issue a token pair : %+v : %v", failed, testID, login, err)
+			}
+			if claims.Subject != "seed" || claims.Issuer != "seed project" {
+				t.Fatalf("\t%s\tTest %d:\tShould issue the user's claims : %+v", failed, testID, claims)
+			}
+			t.Logf("\t%s\tTest %d:\tShould issue a token pair.", success, testID)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tWhen refreshing tokens.", testID)
+		{
+			refreshed := call("Refresh", tokens.RefreshRequest{RefreshToken: login.RefreshToken})
+			if refreshed.Error != "" || refreshed.RefreshToken == login.RefreshToken {
+				t.Fatalf("\t%s\tTest %d:\tShould rotate the refresh token : %+v", failed, testID, refreshed)
+			}
+			t.Logf("\t%s\tTest %d:\tShould rotate the refresh token.", success, testID)
+
+			if resp := call("Refresh", tokens.RefreshRequest{RefreshToken: login.RefreshToken}); resp.Error != "refresh token reuse detected" {
+				t.Fatalf("\t%s\tTest %d:\tShould detect reuse : %+v", failed, testID, resp)
+			}
+			if resp := call("Refresh", tokens.RefreshRequest{RefreshToken: refreshed.RefreshToken}); resp.Error == "" {
+				t.Fatalf("\t%s\tTest %d:\tShould revoke the rotated token on reuse : %+v", failed, testID, resp)
+			}
+			t.Logf("\t%s\tTest %d:\tShould revoke every rotated token on reuse.", success, testID)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tWhen the roles of the user change.", testID)
+		{
+			login = call("Login", tokens.LoginRequest{Username: "seed", Password: "secret"})
+			authn.setRoles(auth.RoleAdmin)
+
+			login = call("Refresh", tokens.RefreshRequest{RefreshToken: login.RefreshToken})
+			claims, err := a.ValidateToken(login.AccessToken)
+			if err != nil || !claims.Authorized(auth.RoleAdmin) || claims.Authorized(auth.RoleUser) {
+				t.Fatalf("\t%s\tTest %d:\tShould issue the current roles : %+v : %v", failed, testID, claims, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould issue the current roles.", success, testID)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tWhen the claims of the user can not be loaded.", testID)
+		{
+			authn.setErr(errors.New("database unavailable"))
+			if resp := call("Refresh", tokens.RefreshRequest{RefreshToken: login.RefreshToken}); resp.AccessToken != "" {
+				t.Fatalf("\t%s\tTest %d:\tShould not refresh the login : %+v", failed, testID, resp)
+			}
+			authn.setErr(nil)
+
+			login = call("Refresh", tokens.RefreshRequest{RefreshToken: login.RefreshToken})
+			if login.Error != "" || login.AccessToken == "" {
+				t.Fatalf("\t%s\tTest %d:\tShould refresh the login on retry : %+v", failed, testID, login)
+			}
+			t.Logf("\t%s\tTest %d:\tShould refresh the login on retry.", success, testID)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tWhen every token of the user is revoked.", testID)
+		{
+			revocations.RevokeSubject("seed", time.Now().Add(time.Second))
+			if resp := call("Refresh", tokens.RefreshRequest{RefreshToken: login.RefreshToken}); resp.Error != "refresh token revoked" {
+				t.Fatalf("\t%s\tTest %d:\tShould not refresh the login : %+v", failed, testID, resp)
+			}
+			t.Logf("\t%s\tTest %d:\tShould not refresh the login.", success, testID)
+		}
+	}
+}
+
+// =============================================================================
+
+type authenticator struct {
+	mu    sync.Mutex
+	roles []string
+	err   error
+}
+
+func (a *authenticator) Authenticate(ctx context.Context, c tokens.Credentials) (auth.Claims, error) {
+	if c.Username != "seed" || c.Password != "secret" {
+		return auth.Claims{}, tokens.ErrInvalidCredentials
+	}
+	return a.LoadClaims(ctx, c.Username)
+}
+
+func (a *authenticator) LoadClaims(ctx context.Context, username string) (auth.Claims, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.err != nil {
+		return auth.Claims{}, a.err
+	}
+	return auth.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{Subject: username},
+		Roles:            a.roles,
+	}, nil
+}
+
+func (a *authenticator) setRoles(roles ...string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.roles = roles
+}
+
+func (a *authenticator) setErr(err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.err = err
+}
@@ -0,0 +1,114 @@
+package tokens
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gitamped/seed/auth"
+)
+
+// ErrNotFound is returned when a refresh token does not exist.
+var ErrNotFound = errors.New("refresh token not found")
+
+// RefreshToken is the stored state of an issued refresh token. Only a hash
+// of the token secret is stored.
+type RefreshToken struct {
+	ID string
+	// Family is shared by every token rotated from the same login.
+	Family string
+	// Username and LoginAt identify the login of the family.
+	Username  string
+	LoginAt   time.Time
+	Hash      []byte
+	Claims    auth.Claims
+	ExpiresAt time.Time
+	Used      bool
+	Revoked   bool
+}
+
+// RefreshStore declares a method set of behavior for persisting refresh
+// tokens. Implementations must be safe for concurrent use.
+type RefreshStore interface {
+	Create(ctx context.Context, rt RefreshToken) error
+	Get(ctx context.Context, id string) (RefreshToken, error)
+	// MarkUsed atomically marks the token used. It returns false if the
+	// token had already been used.
+	MarkUsed(ctx context.Context, id string) (bool, error)
+	// RevokeFamily revokes every token of the family.
+	RevokeFamily(ctx context.Context, family string) error
+}
+
+// MemoryStore is an in-memory RefreshStore for a single process.
+type MemoryStore struct {
+	mu    sync.Mutex
+	store map[string]RefreshToken
+}
+
+// NewMemoryStore constructs an empty MemoryStore ready for use.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		store: make(map[string]RefreshToken),
+	}
+}
+
+// Create implements the RefreshStore interface.
+func (ms *MemoryStore) Create(ctx context.Context, rt RefreshToken) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	// Discard expired tokens as new ones are issued.
+	now := time.Now()
+	for id, t := range ms.store {
+		if now.After(t.ExpiresAt) {
+			delete(ms.store, id)
+		}
+	}
+
+	ms.store[rt.ID] = rt
+	return nil
+}
+
+// Get implements the RefreshStore interface.
+func (ms *MemoryStore) Get(ctx context.Context, id string) (RefreshToken, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	rt, found := ms.store[id]
+	if !found {
+		return RefreshToken{}, ErrNotFound
+	}
+	return rt, nil
+}
+
+// MarkUsed implements the RefreshStore interface.
+func (ms *MemoryStore) MarkUsed(ctx context.Context, id string) (bool, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	rt, found := ms.store[id]
+	if !found {
+		return false, ErrNotFound
+	}
+	if rt.Used {
+		return false, nil
+	}
+	rt.Used = true
+	ms.store[id] = rt
+	return true, nil
+}
+
+// RevokeFamily implements the RefreshStore interface.
+func (ms *MemoryStore) RevokeFamily(ctx context.Context, family string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	for id, rt := range ms.store {
+		if rt.Family == family {
+			rt.Revoked = true
+			ms.store[id] = rt
+		}
+	}
+	return nil
+}
@@ -0,0 +1,364 @@
+package totp
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/gitamped/seed/auth"
+	"github.com/gitamped/seed/server"
+	"github.com/gitamped/seed/tokens"
+	"github.com/gitamped/seed/validate"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Set of errors returned by the TOTPService.
+var (
+	ErrInvalidCode = errors.New("invalid code")
+	ErrEnrolled    = errors.New("second factor already enrolled")
+	ErrActor       = errors.New("impersonated tokens can not be stepped up")
+	ErrLocked      = errors.New("too many invalid codes, try again later")
+)
+
+// Config configures the TOTPService.
+type Config struct {
+	// Issuer is shown by authenticator apps.
+	Issuer string
+	// Period is the lifetime of a code, in whole seconds. Default: 30s
+	Period time.Duration
+	// Skew is the number of periods before and after now a code is
+	// accepted for, to allow for clock drift. A negative value only accepts
+	// codes of the current period. Default: 1
+	Skew int
+	// RecoveryCodes is the number of recovery codes issued. Default: 10
+	RecoveryCodes int
+	// StepUpTTL is the lifetime of stepped up access tokens. They never
+	// outlive the token they were stepped up from. Default: 15m
+	StepUpTTL time.Duration
+	// MaxFailures is the number of invalid codes after which the subject
+	// is locked out. Default: 5
+	MaxFailures int
+	// Lockout is how long the subject is locked out for. It doubles with
+	// every further invalid code, up to 64 times, until a code is accepted.
+	// Default: 1m
+	Lockout time.Duration
+	// Roles may call the service. Default: ADMIN and USER
+	Roles []string
+}
+
+// TOTPServicer implements the TOTPService rpc service.
+type TOTPServicer struct {
+	auth  *auth.Auth
+	store Store
+	cfg   Config
+
+	// locks serialize changes to the enrollment of a subject, so a code can
+	// only be used once. Subjects are hashed onto the locks so they rarely
+	// wait on each other's store I/O. Stores shared between instances must
+	// serialize saves themselves.
+	locks [64]sync.Mutex
+}
+
+// NewTOTPServicer constructs a TOTPServicer.
+func NewTOTPServicer(a *auth.Auth, store Store, cfg Config) (*TOTPServicer, error) {
+	if cfg.Period <= 0 {
+		cfg.Period = 30 * time.Second
+	}
+	if cfg.Period%time.Second != 0 {
+		return nil, fmt.Errorf("period %s is not a whole number of seconds", cfg.Period)
+	}
+	if cfg.Skew < 0 {
+		cfg.Skew = 0
+	} else if cfg.Skew == 0 {
+		cfg.Skew = 1
+	}
+	if cfg.RecoveryCodes <= 0 {
+		cfg.RecoveryCodes = 10
+	}
+	if cfg.StepUpTTL <= 0 {
+		cfg.StepUpTTL = 15 * time.Minute
+	}
+	if cfg.MaxFailures <= 0 {
+		cfg.MaxFailures = 5
+	}
+	if cfg.Lockout <= 0 {
+		cfg.Lockout = time.Minute
+	}
+	if len(cfg.Roles) == 0 {
+		cfg.Roles = []string{auth.RoleAdmin, auth.RoleUser}
+	}
+
+	ts := TOTPServicer{
+		auth:  a,
+		store: store,
+		cfg:   cfg,
+	}
+
+	return &ts, nil
+}
+
+// lock locks the enrollment of the subject and returns the unlock function.
+func (ts *TOTPServicer) lock(subject string) func() {
+	h := fnv.New32a()
+	h.Write([]byte(subject))
+	mu := &ts.locks[h.Sum32()%uint32(len(ts.locks))]
+	mu.Lock()
+	return mu.Unlock
+}
+
+// fail records an invalid code for the enrollment, locking the subject out
+// once it had too many.
+func (ts *TOTPServicer) fail(ctx context.Context, e Enrollment, now time.Time) error {
+	e.Failures++
+	if over := e.Failures - ts.cfg.MaxFailures; over >= 0 {
+		if over > 6 {
+			over = 6
+		}
+		e.LockedUntil = now.Add(ts.cfg.Lockout << over)
+	}
+	if err := ts.store.Save(ctx, e); err != nil {
+		return fmt.Errorf("saving enrollment: %w", err)
+	}
+	return ErrInvalidCode
+}
+
+// Register implements the server.RPCService interface.
+func (ts *TOTPServicer) Register(s *server.Server) {
+	s.Register("TOTPService", "Enroll", server.RPCEndpoint{Roles: ts.cfg.Roles, Handler: ts.EnrollHandler})
+	s.Register("TOTPService", "Confirm", server.RPCEndpoint{Roles: ts.cfg.Roles, Handler: ts.ConfirmHandler})
+	s.Register("TOTPService", "StepUp", server.RPCEndpoint{Roles: ts.cfg.Roles, Handler: ts.StepUpHandler})
+}
+
+// CodeRequest is the request object for TOTPService.Confirm and
+// TOTPService.StepUp. Code is a code from the authenticator app or, for
+// StepUp, a recovery code.
+type CodeRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+// EnrollResponse is the response object for TOTPService.Enroll.
+type EnrollResponse struct {
+	Secret        string   `json:"secret,omitempty"`
+	URL           string   `json:"url,omitempty"`
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+	// Error message if request was not successful
+	Error string `json:"error,omitempty"`
+}
+
+// ConfirmResponse is the response object for TOTPService.Confirm.
+type ConfirmResponse struct {
+	Confirmed bool `json:"confirmed"`
+	// Error message if request was not successful
+	Error string `json:"error,omitempty"`
+}
+
+// EnrollHandler calls Enroll for the caller.
+func (ts *TOTPServicer) EnrollHandler(g server.GenericRequest, b []byte) (any, error) {
+	if g.Claims.Impersonated() {
+		return EnrollResponse{Error: ErrActor.Error()}, nil
+	}
+	return ts.Enroll(g.Ctx, g.Subject())
+}
+
+// Enroll starts the enrollment of the subject, replacing any unconfirmed
+// enrollment. The enrollment must be confirmed before it can be used.
+func (ts *TOTPServicer) Enroll(ctx context.Context, subject string) (EnrollResponse, error) {
+	defer ts.lock(subject)()
+
+	e, err := ts.store.Enrollment(ctx, subject)
+	switch {
+	case err == nil && e.Confirmed:
+		return EnrollResponse{Error: ErrEnrolled.Error()}, nil
+	case err != nil && !errors.Is(err, ErrNotEnrolled):
+		return EnrollResponse{}, fmt.Errorf("looking up enrollment: %w", err)
+	}
+
+	secret, err := NewSecret()
+	if err != nil {
+		return EnrollResponse{}, err
+	}
+	codes, hashes, err := NewRecoveryCodes(ts.cfg.RecoveryCodes)
+	if err != nil {
+		return EnrollResponse{}, err
+	}
+
+	e = Enrollment{Subject: subject, Secret: secret, RecoveryCodes: hashes}
+	if err := ts.store.Save(ctx, e); err != nil {
+		return EnrollResponse{}, fmt.Errorf("saving enrollment: %w", err)
+	}
+
+	resp := EnrollResponse{
+		Secret:        secret,
+		URL:           URL(ts.cfg.Issuer, subject, secret, ts.cfg.Period),
+		RecoveryCodes: codes,
+	}
+
+	return resp, nil
+}
+
+// ConfirmHandler validates input data prior to calling Confirm.
+func (ts *TOTPServicer) ConfirmHandler(g server.GenericRequest, b []byte) (any, error) {
+	var req CodeRequest
+	if err := json.Unmarshal(b, &req); err != nil {
+		return ConfirmResponse{Error: "Invalid CodeRequest data."}, nil
+	}
+	if err := validate.Check(req); err != nil {
+		return ConfirmResponse{Error: fmt.Errorf("validating data: %w", err).Error()}, nil
+	}
+	if g.Claims.Impersonated() {
+		return ConfirmResponse{Error: ErrActor.Error()}, nil
+	}
+
+	return ts.Confirm(g.Ctx, g.Subject(), req.Code)
+}
+
+// Confirm completes the enrollment of the subject with a code from the
+// authenticator app. Invalid codes count towards a lockout.
+func (ts *TOTPServicer) Confirm(ctx context.Context, subject, code string) (ConfirmResponse, error) {
+	defer ts.lock(subject)()
+
+	e, err := ts.store.Enrollment(ctx, subject)
+	switch {
+	case errors.Is(err, ErrNotEnrolled):
+		return ConfirmResponse{Error: ErrNotEnrolled.Error()}, nil
+	case err != nil:
+		return ConfirmResponse{}, fmt.Errorf("looking up enrollment: %w", err)
+	case e.Confirmed:
+		return ConfirmResponse{Error: ErrEnrolled.Error()}, nil
+	}
+
+	now := time.Now()
+	if now.Before(e.LockedUntil) {
+		return ConfirmResponse{Error: ErrLocked.Error()}, nil
+	}
+
+	step, ok := Match(e.Secret, code, now, ts.cfg.Period, ts.cfg.Skew)
+	if !ok {
+		if err := ts.fail(ctx, e, now); !errors.Is(err, ErrInvalidCode) {
+			return ConfirmResponse{}, err
+		}
+		return ConfirmResponse{Error: ErrInvalidCode.Error()}, nil
+	}
+
+	e.Confirmed = true
+	e.LastStep = step
+	e.Failures = 0
+	e.LockedUntil = time.Time{}
+	if err := ts.store.Save(ctx, e); err != nil {
+		return ConfirmResponse{}, fmt.Errorf("saving enrollment: %w", err)
+	}
+
+	return ConfirmResponse{Confirmed: true}, nil
+}
+
+// StepUpHandler validates input data prior to calling StepUp.
+func (ts *TOTPServicer) StepUpHandler(g server.GenericRequest, b []byte) (any, error) {
+	var req CodeRequest
+	if err := json.Unmarshal(b, &req); err != nil {
+		return tokens.TokenResponse{Error: "Invalid CodeRequest data."}, nil
+	}
+	if err := validate.Check(req); err != nil {
+		return tokens.TokenResponse{Error: fmt.Errorf("validating data: %w", err).Error()}, nil
+	}
+
+	return ts.StepUp(g.Ctx, g.Claims, req.Code)
+}
+
+// StepUp verifies the second factor of the subject of the claims and issues
+// an access token recording it in the amr claim.
+func (ts *TOTPServicer) StepUp(ctx context.Context, claims auth.Claims, code string) (tokens.TokenResponse, error) {
+	if claims.Impersonated() {
+		return tokens.TokenResponse{Error: ErrActor.Error()}, nil
+	}
+
+	if err := ts.Verify(ctx, claims.Subject, code); err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidCode) || errors.Is(err, ErrNotEnrolled):
+			return tokens.TokenResponse{Error: ErrInvalidCode.Error()}, nil
+		case errors.Is(err, ErrLocked):
+			return tokens.TokenResponse{Error: ErrLocked.Error()}, nil
+		}
+		return tokens.TokenResponse{}, err
+	}
+
+	now := time.Now()
+	exp := now.Add(ts.cfg.StepUpTTL)
+	if claims.ExpiresAt != nil && claims.ExpiresAt.Before(exp) {
+		exp = claims.ExpiresAt.Time
+	}
+
+	claims.ID = ""
+	claims.IssuedAt = jwt.NewNumericDate(now)
+	claims.ExpiresAt = jwt.NewNumericDate(exp)
+	if !claims.SteppedUp() {
+		claims.AMR = append(append([]string(nil), claims.AMR...), auth.AMROTP, auth.AMRMFA)
+	}
+
+	token, err := ts.auth.GenerateToken(claims)
+	if err != nil {
+		return tokens.TokenResponse{}, fmt.Errorf("generating token: %w", err)
+	}
+
+	resp := tokens.TokenResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(exp.Sub(now).Seconds()),
+	}
+
+	return resp, nil
+}
+
+// Verify checks a code from the authenticator app, or a recovery code, of
+// the subject. Each code can only be used once. After Config.MaxFailures
+// invalid codes the subject is locked out and ErrLocked is returned.
+func (ts *TOTPServicer) Verify(ctx context.Context, subject, code string) error {
+	defer ts.lock(subject)()
+
+	e, err := ts.store.Enrollment(ctx, subject)
+	switch {
+	case errors.Is(err, ErrNotEnrolled):
+		return ErrNotEnrolled
+	case err != nil:
+		return fmt.Errorf("looking up enrollment: %w", err)
+	case !e.Confirmed:
+		return ErrNotEnrolled
+	}
+
+	now := time.Now()
+	if now.Before(e.LockedUntil) {
+		return ErrLocked
+	}
+
+	if step, ok := Match(e.Secret, code, now, ts.cfg.Period, ts.cfg.Skew); ok {
+		if step <= e.LastStep {
+			return ts.fail(ctx, e, now)
+		}
+		e.LastStep = step
+		e.Failures = 0
+		e.LockedUntil = time.Time{}
+		if err := ts.store.Save(ctx, e); err != nil {
+			return fmt.Errorf("saving enrollment: %w", err)
+		}
+		return nil
+	}
+
+	hash := hashRecoveryCode(code)
+	for i, h := range e.RecoveryCodes {
+		if subtle.ConstantTimeCompare([]byte(h), []byte(hash)) == 1 {
+			e.RecoveryCodes = append(e.RecoveryCodes[:i], e.RecoveryCodes[i+1:]...)
+			e.Failures = 0
+			e.LockedUntil = time.Time{}
+			if err := ts.store.Save(ctx, e); err != nil {
+				return fmt.Errorf("saving enrollment: %w", err)
+			}
+			return nil
+		}
+	}
+
+	return ts.fail(ctx, e, now)
+}
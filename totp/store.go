@@ -0,0 +1,72 @@
+package totp
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrNotEnrolled is returned when the subject has no enrollment.
+var ErrNotEnrolled = errors.New("second factor not enrolled")
+
+// Enrollment is the second factor of a subject.
+type Enrollment struct {
+	Subject string
+	Secret  string
+	// Confirmed is set once a code from the authenticator app has been
+	// verified. Unconfirmed enrollments can not be used to step up.
+	Confirmed bool
+	// LastStep is the time step of the last accepted code, so codes can not
+	// be replayed.
+	LastStep int64
+	// RecoveryCodes are the hashes of the unused recovery codes.
+	RecoveryCodes []string
+	// Failures is the number of invalid codes since a code was last
+	// accepted.
+	Failures int
+	// LockedUntil is when codes are accepted again after too many
+	// failures.
+	LockedUntil time.Time
+}
+
+// Store declares a method set of behavior for keeping enrollments.
+type Store interface {
+	Enrollment(ctx context.Context, subject string) (Enrollment, error)
+	Save(ctx context.Context, e Enrollment) error
+}
+
+// MemoryStore is an in-memory enrollment store.
+type MemoryStore struct {
+	mu          sync.RWMutex
+	enrollments map[string]Enrollment
+}
+
+// NewMemoryStore constructs an empty MemoryStore ready for use.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		enrollments: make(map[string]Enrollment),
+	}
+}
+
+// Enrollment implements the Store interface.
+func (ms *MemoryStore) Enrollment(ctx context.Context, subject string) (Enrollment, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	e, ok := ms.enrollments[subject]
+	if !ok {
+		return Enrollment{}, ErrNotEnrolled
+	}
+	e.RecoveryCodes = append([]string(nil), e.RecoveryCodes...)
+	return e, nil
+}
+
+// Save implements the Store interface.
+func (ms *MemoryStore) Save(ctx context.Context, e Enrollment) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ms.enrollments[e.Subject] = e
+	return nil
+}
@@ -0,0 +1,261 @@
+package totp_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base32"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gitamped/seed/auth"
+	"github.com/gitamped/seed/keystore"
+	"github.com/gitamped/seed/mid"
+	"github.com/gitamped/seed/server"
+	"github.com/gitamped/seed/tokens"
+	"github.com/gitamped/seed/totp"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+func Test_Code(t *testing.T) {
+	t.Log("Given the need to generate codes as described in RFC 6238.")
+	{
+		secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+		// The SHA-1 test vectors of RFC 6238 appendix B, truncated to 6 digits.
+		ttable := []struct {
+			Time int64
+			Code string
+		}{
+			{59, "287082"},
+			{1111111109, "081804"},
+			{1234567890, "005924"},
+			{2000000000, "279037"},
+		}
+
+		for testID, td := range ttable {
+			t.Logf("\tTest %d:\tWhen the time is %d.", testID, td.Time)
+			{
+				code, err := totp.Code(secret, totp.Step(time.Unix(td.Time, 0), 30*time.Second))
+				if err != nil || code != td.Code {
+					t.Fatalf("\t%s\tTest %d:\tShould generate code %s : %s %v", failed, testID, td.Code, code, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould generate code %s.", success, testID, td.Code)
+			}
+		}
+	}
+}
+
+func Test_StepUp(t *testing.T) {
+	t.Log("Given the need to require a second factor for sensitive rpcs.")
+	{
+		a, err := auth.NewHMAC("v1", keystore.NewSecretMap(map[string][]byte{"v1": []byte("0123456789abcdef0123456789abcdef")}))
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to create an authenticator : %v", failed, err)
+		}
+
+		mw := append([]mid.Middleware{mid.AuthMiddleware(a)}, mid.CommonMiddleware...)
+		s := server.NewServer(mw)
+		ts, err := totp.NewTOTPServicer(a, totp.NewMemoryStore(), totp.Config{Issuer: "seed"})
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to create the service : %v", failed, err)
+		}
+		ts.Register(s)
+		s.Register("AdminService", "Delete", server.RPCEndpoint{
+			Roles:  []string{auth.RoleAdmin},
+			StepUp: true,
+			Handler: func(g server.GenericRequest, b []byte) (any, error) {
+				return struct{}{}, nil
+			},
+		})
+
+		token, _ := a.GenerateToken(auth.Claims{
+			RegisteredClaims: jwt.RegisteredClaims{
+				Subject:   "admin",
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			},
+			Roles: []string{auth.RoleAdmin},
+			AMR:   []string{auth.AMRPassword},
+		})
+
+		call := func(path, token, body string, resp any) *httptest.ResponseRecorder {
+			r := httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(body))
+			r.Header.Set("Authorization", "Bearer "+token)
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, r)
+			if resp != nil {
+				json.Unmarshal(w.Body.Bytes(), resp)
+			}
+			return w
+		}
+
+		t.Logf("\tTest %d:\tWhen calling without a second factor.", 0)
+		{
+			w := call("/v1/AdminService.Delete", token, `{}`, nil)
+			if w.Code != http.StatusUnauthorized || !strings.Contains(w.Header().Get("WWW-Authenticate"), "insufficient_user_authentication") {
+				t.Fatalf("\t%s\tTest %d:\tShould ask for a second factor : %d %s", failed, 0, w.Code, w.Header().Get("WWW-Authenticate"))
+			}
+			t.Logf("\t%s\tTest %d:\tShould ask for a second factor.", success, 0)
+		}
+
+		var enroll totp.EnrollResponse
+		call("/v1/TOTPService.Enroll", token, `{}`, &enroll)
+		if enroll.Secret == "" || len(enroll.RecoveryCodes) != 10 || !strings.HasPrefix(enroll.URL, "otpauth://totp/") {
+			t.Fatalf("\t%s\tShould be able to enroll : %+v", failed, enroll)
+		}
+		t.Logf("\t%s\tShould be able to enroll.", success)
+
+		now := totp.Step(time.Now(), 30*time.Second)
+		current, _ := totp.Code(enroll.Secret, now)
+		next, _ := totp.Code(enroll.Secret, now+1)
+
+		var confirm totp.ConfirmResponse
+		call("/v1/TOTPService.Confirm", token, `{"code": "`+current+`"}`, &confirm)
+		if !confirm.Confirmed {
+			t.Fatalf("\t%s\tShould be able to confirm the enrollment : %+v", failed, confirm)
+		}
+		t.Logf("\t%s\tShould be able to confirm the enrollment.", success)
+
+		ttable := []struct {
+			TestTitle string
+			Code      string
+			Success   bool
+		}{
+			{"When replaying a used code", current, false},
+			{"When the code is wrong", "000000", false},
+			{"When the code is valid", next, true},
+			{"When using a recovery code", enroll.RecoveryCodes[0], true},
+			{"When reusing a recovery code", enroll.RecoveryCodes[0], false},
+		}
+
+		for i, td := range ttable {
+			testID := i + 1
+			t.Logf("\tTest %d:\t%s.", testID, td.TestTitle)
+			{
+				var resp tokens.TokenResponse
+				call("/v1/TOTPService.StepUp", token, `{"code": "`+td.Code+`"}`, &resp)
+				if (resp.AccessToken != "") != td.Success {
+					t.Fatalf("\t%s\tTest %d:\tShould step up %v : %+v", failed, testID, td.Success, resp)
+				}
+				if !td.Success {
+					t.Logf("\t%s\tTest %d:\tShould step up %v.", success, testID, td.Success)
+					continue
+				}
+
+				if w := call("/v1/AdminService.Delete", resp.AccessToken, `{}`, nil); w.Code != http.StatusOK {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to call the rpc : %d", failed, testID, w.Code)
+				}
+				t.Logf("\t%s\tTest %d:\tShould step up %v.", success, testID, td.Success)
+			}
+		}
+	}
+}
+
+func Test_Lockout(t *testing.T) {
+	t.Log("Given the need to stop guessing of codes.")
+	{
+		ctx := context.Background()
+		store := totp.NewMemoryStore()
+		ts, err := totp.NewTOTPServicer(nil, store, totp.Config{MaxFailures: 2, Lockout: time.Minute})
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to create the service : %v", failed, err)
+		}
+
+		enroll, err := ts.Enroll(ctx, "admin")
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to enroll : %v", failed, err)
+		}
+		now := totp.Step(time.Now(), 30*time.Second)
+		current, _ := totp.Code(enroll.Secret, now)
+		next, _ := totp.Code(enroll.Secret, now+1)
+		if confirm, err := ts.Confirm(ctx, "admin", current); err != nil || !confirm.Confirmed {
+			t.Fatalf("\t%s\tShould be able to confirm the enrollment : %v %+v", failed, err, confirm)
+		}
+
+		ttable := []struct {
+			TestTitle string
+			Code      string
+			Expected  error
+		}{
+			{"When the first code is wrong", "000000", totp.ErrInvalidCode},
+			{"When a code is replayed", current, totp.ErrInvalidCode},
+			{"When a valid code follows too many failures", next, totp.ErrLocked},
+			{"When a recovery code follows too many failures", enroll.RecoveryCodes[0], totp.ErrLocked},
+		}
+
+		for testID, td := range ttable {
+			t.Logf("\tTest %d:\t%s.", testID, td.TestTitle)
+			{
+				if err := ts.Verify(ctx, "admin", td.Code); !errors.Is(err, td.Expected) {
+					t.Fatalf("\t%s\tTest %d:\tShould fail with %v : %v", failed, testID, td.Expected, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould fail with %v.", success, testID, td.Expected)
+			}
+		}
+
+		testID := len(ttable)
+		t.Logf("\tTest %d:\tWhen the lockout is over.", testID)
+		{
+			e, _ := store.Enrollment(ctx, "admin")
+			if e.Failures != 2 || time.Until(e.LockedUntil) <= 0 || time.Until(e.LockedUntil) > time.Minute {
+				t.Fatalf("\t%s\tTest %d:\tShould have locked the subject out for a minute : %+v", failed, testID, e)
+			}
+			e.LockedUntil = time.Now()
+			store.Save(ctx, e)
+
+			if err := ts.Verify(ctx, "admin", "000000"); !errors.Is(err, totp.ErrInvalidCode) {
+				t.Fatalf("\t%s\tTest %d:\tShould reject the wrong code : %v", failed, testID, err)
+			}
+			e, _ = store.Enrollment(ctx, "admin")
+			if time.Until(e.LockedUntil) <= time.Minute {
+				t.Fatalf("\t%s\tTest %d:\tShould double the lockout : %v", failed, testID, time.Until(e.LockedUntil))
+			}
+
+			e.LockedUntil = time.Now()
+			store.Save(ctx, e)
+			if err := ts.Verify(ctx, "admin", next); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould accept a valid code : %v", failed, testID, err)
+			}
+			if e, _ = store.Enrollment(ctx, "admin"); e.Failures != 0 || !e.LockedUntil.IsZero() {
+				t.Fatalf("\t%s\tTest %d:\tShould reset the failures : %+v", failed, testID, e)
+			}
+			t.Logf("\t%s\tTest %d:\tShould back off and reset once a code is accepted.", success, testID)
+		}
+	}
+}
+
+func Test_Period(t *testing.T) {
+	t.Log("Given the need to configure the lifetime of codes.")
+	{
+		ttable := []struct {
+			Period  time.Duration
+			Success bool
+		}{
+			{0, true},
+			{time.Minute, true},
+			{500 * time.Millisecond, false},
+			{1500 * time.Millisecond, false},
+		}
+
+		for testID, td := range ttable {
+			t.Logf("\tTest %d:\tWhen the period is %s.", testID, td.Period)
+			{
+				_, err := totp.NewTOTPServicer(nil, totp.NewMemoryStore(), totp.Config{Period: td.Period})
+				if (err == nil) != td.Success {
+					t.Fatalf("\t%s\tTest %d:\tShould accept the period %v : %v", failed, testID, td.Success, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould accept the period %v.", success, testID, td.Success)
+			}
+		}
+	}
+}
@@ -24,6 +24,12 @@ type Values struct {
 	TraceID    string
 	Now        time.Time
 	StatusCode int
+	// DenyReason explains why the request was denied, for logging.
+	DenyReason string
+	// Actor is who made the request and Subject who it was made for. They
+	// differ when the actor is impersonating the subject.
+	Actor   string
+	Subject string
 }
 
 // GetValues returns the values from the context.
@@ -70,3 +76,25 @@ func SetStatusCode(ctx context.Context, statusCode int) error {
 	v.StatusCode = statusCode
 	return nil
 }
+
+// SetDenyReason sets the reason the request was denied back into the context.
+func SetDenyReason(ctx context.Context, reason string) error {
+	v, ok := ctx.Value(key).(*Values)
+	if !ok {
+		return errors.New("web value missing from context")
+	}
+	v.DenyReason = reason
+	return nil
+}
+
+// SetIdentity sets who made the request and who it was made for back into
+// the context.
+func SetIdentity(ctx context.Context, actor, subject string) error {
+	v, ok := ctx.Value(key).(*Values)
+	if !ok {
+		return errors.New("web value missing from context")
+	}
+	v.Actor = actor
+	v.Subject = subject
+	return nil
+}